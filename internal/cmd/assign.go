@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/warrant-dev/warrant-cli/internal/printer"
 	"github.com/warrant-dev/warrant-cli/internal/reader"
 	"github.com/warrant-dev/warrant-go/v6"
 )
@@ -35,32 +36,40 @@ warrant assign user:1 editor document:xyz
 warrant assign user:56 member role:admin 'domain == warrant.dev'`,
 	Args: cobra.RangeArgs(3, 4),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
 
 		warrantSpec, err := reader.ReadWarrantArgs(args)
 		if err != nil {
 			return err
 		}
 
-		_, err = warrant.Create(warrantSpec)
+		ConfirmChangeOrExit(fmt.Sprintf("assign %s", warrantAsString(warrantSpec)))
+		newWarrant, err := warrant.Create(warrantSpec)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("assigned %s\n", warrantAsString(warrantSpec))
+		printer.PrintChange(newWarrant, func() {
+			fmt.Printf("assigned %s\n", warrantAsString(warrantSpec))
+		})
 
 		return nil
 	},
 }
 
 func warrantAsString(w *warrant.WarrantParams) string {
-	subject := fmt.Sprintf("%s:%s", w.Subject.ObjectType, w.Subject.ObjectId)
-	if w.Subject.Relation != "" {
-		subject = fmt.Sprintf("%s#%s", subject, w.Subject.Relation)
-	}
-	s := fmt.Sprintf("%s %s %s:%s", subject, w.Relation, w.ObjectType, w.ObjectId)
+	s := fmt.Sprintf("%s %s %s:%s", subjectAsString(w.Subject), w.Relation, w.ObjectType, w.ObjectId)
 	if w.Policy != "" {
 		s = fmt.Sprintf("%s %s", s, w.Policy)
 	}
 
 	return s
 }
+
+func subjectAsString(subject warrant.Subject) string {
+	s := fmt.Sprintf("%s:%s", subject.ObjectType, subject.ObjectId)
+	if subject.Relation != "" {
+		s = fmt.Sprintf("%s#%s", s, subject.Relation)
+	}
+
+	return s
+}
@@ -0,0 +1,340 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/warrant-dev/warrant-cli/internal/printer"
+	"github.com/warrant-dev/warrant-cli/internal/reader"
+	"github.com/warrant-dev/warrant-go/v6"
+)
+
+var batchFile string
+var batchFormat string
+var batchColumnMappings []string
+var batchObjectType string
+var batchSize int
+var batchConcurrency int
+var batchFailuresFile string
+
+// Error recorded for rows that weren't processed because the run was stopped early
+var errBatchStopped = errors.New("not processed, the run was stopped early")
+
+// Add flags for commands that operate on objects read from a file (e.g. object import)
+func addBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&batchFile, "file", "f", "", "csv, ndjson or text (one 'type:id', or id with --type, per line) file of objects, or '-' for stdin")
+	cmd.Flags().StringVar(&batchFormat, "format", "", "format of --file: csv, ndjson or txt (detected from the file extension by default)")
+	cmd.Flags().StringArrayVar(&batchColumnMappings, "map", nil, "map csv columns to object fields, e.g. 'type=kind,id=user_id,meta.name=full_name' (defaults to the objectType and objectId columns, with all other columns as meta)")
+	cmd.Flags().StringVarP(&batchObjectType, "type", "t", "", "object type for rows that don't specify one")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 100, "number of objects per batch request")
+	cmd.Flags().IntVar(&batchConcurrency, "concurrency", 4, "maximum number of batch requests in flight")
+	cmd.Flags().StringVar(&batchFailuresFile, "failures-file", "", "csv file to write failed rows to (default is <file>.failures.csv)")
+}
+
+// Read the objects in --file. Rows without an object type get --type, if provided.
+func readBatchObjects() ([]reader.ObjectRow, error) {
+	if batchSize < 1 {
+		return nil, printer.UsageError(fmt.Errorf("invalid --batch-size %d, must be at least 1", batchSize))
+	}
+	if batchConcurrency < 1 {
+		return nil, printer.UsageError(fmt.Errorf("invalid --concurrency %d, must be at least 1", batchConcurrency))
+	}
+	mapping, err := reader.ReadObjectColumnMapping(batchColumnMappings)
+	if err != nil {
+		return nil, printer.UsageError(err)
+	}
+	format := batchFormat
+	if format == "" {
+		format = reader.ObjectsFileFormat(batchFile)
+	}
+	rows, err := reader.ReadObjectsFile(batchFile, format, mapping)
+	if err != nil {
+		return nil, printer.NewError(printer.ExitValidation, err)
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if row.Err != nil {
+			continue
+		}
+		if row.Object.ObjectType == "" {
+			row.Object.ObjectType = batchObjectType
+		}
+		if row.Object.ObjectType == "" {
+			row.Err = fmt.Errorf("missing object type (provide a default with --type)")
+		}
+	}
+	return rows, nil
+}
+
+// A row that failed to be read or processed
+type batchFailure struct {
+	Line   int    `json:"line"`
+	Object string `json:"object"`
+	Error  string `json:"error"`
+}
+
+// Summary of a batch operation, e.g. for --output json
+type batchSummary struct {
+	Total        int    `json:"total"`
+	Succeeded    int    `json:"succeeded"`
+	Failed       int    `json:"failed"`
+	FailuresFile string `json:"failuresFile,omitempty"`
+}
+
+// Run fn on rows in batches of --batch-size, with up to --concurrency batches in flight. If a batch is rejected
+// (see isRowError), its objects are retried one at a time so that failures can be reported per row without
+// aborting the whole run. Any other error (e.g. an auth, network or server error) stops the run and is returned,
+// and rows that weren't processed are returned as failures so the run can be resumed from the failures file.
+func runBatches(rows []reader.ObjectRow, verb string, fn func([]warrant.ObjectParams) error) (int, []batchFailure, error) {
+	var failures []batchFailure
+	var valid []reader.ObjectRow
+	for _, row := range rows {
+		if row.Err != nil {
+			failures = append(failures, newBatchFailure(row, row.Err))
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	var batches [][]reader.ObjectRow
+	for start := 0; start < len(valid); start += batchSize {
+		batches = append(batches, valid[start:min(start+batchSize, len(valid))])
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var runErr error
+	stop := make(chan struct{})
+	succeeded := 0
+	progress := newBatchProgress(verb, len(rows))
+	progress.add(len(failures))
+	batchCh := make(chan []reader.ObjectRow)
+	for i := 0; i < batchConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batchCh {
+				batchSucceeded, batchFailures, unprocessed, err := runBatch(batch, fn)
+				mu.Lock()
+				succeeded += batchSucceeded
+				failures = append(failures, batchFailures...)
+				failures = append(failures, unprocessedFailures(unprocessed)...)
+				progress.add(len(batch))
+				if err != nil && runErr == nil {
+					runErr = err
+					close(stop)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	dispatched := 0
+dispatch:
+	for _, batch := range batches {
+		select {
+		case batchCh <- batch:
+			dispatched++
+		case <-stop:
+			break dispatch
+		}
+	}
+	close(batchCh)
+	wg.Wait()
+	progress.done()
+
+	for _, batch := range batches[dispatched:] {
+		failures = append(failures, unprocessedFailures(batch)...)
+	}
+
+	return succeeded, failures, runErr
+}
+
+// Run fn on a single batch. If the run has to be stopped, the rows of the batch that weren't processed are returned
+// along with the error.
+func runBatch(batch []reader.ObjectRow, fn func([]warrant.ObjectParams) error) (int, []batchFailure, []reader.ObjectRow, error) {
+	objects := make([]warrant.ObjectParams, 0, len(batch))
+	for _, row := range batch {
+		objects = append(objects, row.Object)
+	}
+	err := fn(objects)
+	if err == nil {
+		return len(batch), nil, nil, nil
+	}
+	if !isRowError(err) {
+		return 0, nil, batch, err
+	}
+	if len(batch) == 1 {
+		return 0, []batchFailure{newBatchFailure(batch[0], err)}, nil, nil
+	}
+
+	succeeded := 0
+	var failures []batchFailure
+	for i, row := range batch {
+		err := fn([]warrant.ObjectParams{row.Object})
+		if err != nil && !isRowError(err) {
+			return succeeded, failures, batch[i:], err
+		}
+		if err != nil {
+			failures = append(failures, newBatchFailure(row, err))
+		} else {
+			succeeded++
+		}
+	}
+	return succeeded, failures, nil, nil
+}
+
+// Whether a batch was rejected because of (some of) its objects, i.e. a 4xx validation or not found error.
+// Other errors (e.g. auth, network or server errors) would fail for every object, so retrying them per row is pointless.
+func isRowError(err error) bool {
+	var sdkErr warrant.Error
+	if !errors.As(err, &sdkErr) {
+		return false
+	}
+	exitCode := parseApiError(sdkErr).ExitCode
+	return exitCode == printer.ExitValidation || exitCode == printer.ExitNotFound
+}
+
+func newBatchFailure(row reader.ObjectRow, err error) batchFailure {
+	object := row.Object.ObjectId
+	if row.Object.ObjectType != "" {
+		object = fmt.Sprintf("%s:%s", row.Object.ObjectType, row.Object.ObjectId)
+	}
+	// Not toCliError, which could look up hints (i.e. make more requests) for every failed row
+	msg := err.Error()
+	var sdkErr warrant.Error
+	if errors.As(err, &sdkErr) {
+		msg = parseApiError(sdkErr).Message
+	}
+	return batchFailure{
+		Line:   row.Line,
+		Object: object,
+		Error:  msg,
+	}
+}
+
+func unprocessedFailures(rows []reader.ObjectRow) []batchFailure {
+	failures := make([]batchFailure, 0, len(rows))
+	for _, row := range rows {
+		failures = append(failures, newBatchFailure(row, errBatchStopped))
+	}
+	return failures
+}
+
+// Print a summary of a batch operation and write failed rows (if any) to the failures file. Returns an error if any
+// rows failed, or runErr (with its exit code) if the run was stopped early.
+func reportBatchResult(verb string, total int, succeeded int, failures []batchFailure, runErr error) error {
+	summary := batchSummary{
+		Total:     total,
+		Succeeded: succeeded,
+		Failed:    len(failures),
+	}
+	if len(failures) > 0 {
+		summary.FailuresFile = batchFailuresFile
+		if summary.FailuresFile == "" {
+			summary.FailuresFile = "objects.failures.csv"
+			if batchFile != "-" {
+				summary.FailuresFile = batchFile + ".failures.csv"
+			}
+		}
+		err := writeBatchFailures(summary.FailuresFile, failures)
+		if err != nil {
+			return err
+		}
+	}
+
+	if runErr != nil {
+		cliErr := toCliError(runErr)
+		hint := fmt.Sprintf("the run was stopped early, %d of %d objects were %s", succeeded, total, verb)
+		if summary.FailuresFile != "" {
+			hint = fmt.Sprintf("%s. Failed and unprocessed rows were written to %s", hint, summary.FailuresFile)
+		}
+		cliErr.Hints = append(cliErr.Hints, hint)
+		return cliErr
+	}
+
+	printer.PrintChange(summary, func() {
+		fmt.Printf("%s %d of %d objects\n", verb, succeeded, total)
+		if len(failures) > 0 {
+			fmt.Printf("%d failed, see %s\n", len(failures), summary.FailuresFile)
+		}
+	})
+	if len(failures) > 0 {
+		return printer.NewError(printer.ExitFailure, fmt.Errorf("%d of %d objects failed, see %s", len(failures), total, summary.FailuresFile))
+	}
+	return nil
+}
+
+func writeBatchFailures(path string, failures []batchFailure) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	slices.SortFunc(failures, func(a, b batchFailure) int {
+		return a.Line - b.Line
+	})
+	w := csv.NewWriter(file)
+	err = w.Write([]string{"line", "object", "error"})
+	if err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		err = w.Write([]string{strconv.Itoa(failure.Line), failure.Object, failure.Error})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Reports progress of a batch operation on stderr, if it's a terminal
+type batchProgress struct {
+	verb    string
+	total   int
+	current int
+	enabled bool
+}
+
+func newBatchProgress(verb string, total int) *batchProgress {
+	return &batchProgress{
+		verb:    verb,
+		total:   total,
+		enabled: !printer.Quiet && reader.IsTerminal(os.Stderr),
+	}
+}
+
+func (p *batchProgress) add(n int) {
+	p.current += n
+	if p.enabled {
+		fmt.Fprintf(os.Stderr, "\r%s %d/%d objects", p.verb, p.current, p.total)
+	}
+}
+
+func (p *batchProgress) done() {
+	if p.enabled {
+		fmt.Fprintln(os.Stderr)
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"os"
 	"strconv"
 
-	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 	"github.com/warrant-dev/warrant-cli/internal/printer"
 	"github.com/warrant-dev/warrant-cli/internal/reader"
@@ -42,7 +41,7 @@ func init() {
 var checkCmd = &cobra.Command{
 	Use:   "check <subject> <relation> <object> [context]",
 	Short: "Check if a subject has a given relation with an object",
-	Long:  "Check if a subject (specified as 'type:id') has a given 'relation' with an object (also specified as 'type:id'). Returns 'true' if the relation exists, 'false' otherwise. Checks can also include an optional 'context' (as a json string) for policy evaluation.",
+	Long:  "Check if a subject (specified as 'type:id') has a given 'relation' with an object (also specified as 'type:id'). Returns 'true' if the relation exists, 'false' otherwise. Checks can also include an optional 'context' (as a json string) for policy evaluation. If no context is provided, the environment's default check context (if any) is used.",
 	Example: `
 warrant check user:56 member role:admin
 warrant check user:2 editor document:xyz
@@ -50,7 +49,7 @@ warrant check user:56 member tenant:x '{"clientIp": "192.168.0.1"}'
 warrant check user:56 member role:admin --assert true`,
 	Args: cobra.RangeArgs(3, 4),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
 
 		var assertVal bool
 		if assertFlagVal != "" {
@@ -67,6 +66,13 @@ warrant check user:56 member role:admin --assert true`,
 		}
 		checkSpec.Debug = debug
 
+		// Use the environment's default context if none was provided
+		if len(args) == 3 {
+			if defaults := currentEnvironment().Defaults; defaults != nil && len(defaults.CheckContext) > 0 {
+				checkSpec.WarrantCheck.Context = defaults.CheckContext
+			}
+		}
+
 		if checkWarrantToken != "" {
 			checkSpec.WarrantToken = checkWarrantToken
 		}
@@ -81,27 +87,63 @@ warrant check user:56 member role:admin --assert true`,
 			return err
 		}
 
+		result := checkOutput{
+			Subject:  fmt.Sprintf("%s:%s", checkSpec.WarrantCheck.Subject.GetObjectType(), checkSpec.WarrantCheck.Subject.GetObjectId()),
+			Relation: checkSpec.WarrantCheck.Relation,
+			Object:   fmt.Sprintf("%s:%s", checkSpec.WarrantCheck.Object.GetObjectType(), checkSpec.WarrantCheck.Object.GetObjectId()),
+			Context:  checkSpec.WarrantCheck.Context,
+			Result:   checkResult,
+		}
+
+		// In quiet mode, the result is only reported via the exit code
+		if printer.Quiet {
+			if (assertFlagVal != "" && checkResult != assertVal) || (assertFlagVal == "" && !checkResult) {
+				os.Exit(printer.ExitFailure)
+			}
+			return nil
+		}
+
 		if assertFlagVal != "" {
 			// Assert
-			if checkResult == assertVal {
-				fmt.Printf("%s %s\n", termenv.String(printer.Checkmark, fmt.Sprintf("assert %t", assertVal)).Foreground(printer.Green), checkSpecString)
-			} else {
-				fmt.Printf("%s %s\n", termenv.String(printer.Cross, fmt.Sprintf("assert %t", assertVal)).Foreground(printer.Red), checkSpecString)
-				os.Exit(1)
+			passed := checkResult == assertVal
+			result.Assert = &assertVal
+			result.Passed = &passed
+			printer.PrintResult(result, func() {
+				if passed {
+					fmt.Printf("%s %s\n", printer.Success(fmt.Sprintf("assert %t", assertVal)), checkSpecString)
+				} else {
+					fmt.Printf("%s %s\n", printer.Failure(fmt.Sprintf("assert %t", assertVal)), checkSpecString)
+				}
+			})
+			if !passed {
+				os.Exit(printer.ExitFailure)
 			}
 		} else {
 			// Check
-			if checkResult {
-				fmt.Printf("%s %s\n", termenv.String(printer.Checkmark, "true").Foreground(printer.Green), checkSpecString)
-			} else {
-				fmt.Printf("%s %s\n", termenv.String(printer.Cross, "false").Foreground(printer.Red), checkSpecString)
-			}
+			printer.PrintResult(result, func() {
+				if checkResult {
+					fmt.Printf("%s %s\n", printer.Success("true"), checkSpecString)
+				} else {
+					fmt.Printf("%s %s\n", printer.Failure("false"), checkSpecString)
+				}
+			})
 		}
 
 		return nil
 	},
 }
 
+// Structured result of a check (or assert), e.g. for --output json
+type checkOutput struct {
+	Subject  string                `json:"subject"`
+	Relation string                `json:"relation"`
+	Object   string                `json:"object"`
+	Context  warrant.PolicyContext `json:"context,omitempty"`
+	Result   bool                  `json:"result"`
+	Assert   *bool                 `json:"assert,omitempty"`
+	Passed   *bool                 `json:"passed,omitempty"`
+}
+
 func checkSpecAsString(w *warrant.WarrantCheck) (string, error) {
 	// TODO: should also handle subject relation if present
 	s := fmt.Sprintf(
@@ -0,0 +1,269 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/warrant-dev/warrant-cli/internal/config"
+	"github.com/warrant-dev/warrant-cli/internal/printer"
+	"github.com/warrant-dev/warrant-go/v6"
+	"github.com/warrant-dev/warrant-go/v6/objecttype"
+)
+
+var defaultVerifyTimeout = 10 * time.Second
+
+func init() {
+	envCmd.AddCommand(verifyEnvCmd)
+	rootCmd.AddCommand(doctorCmd)
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose config, connectivity and credential issues",
+	Long:  "Diagnose config, connectivity and credential issues. Checks the config file (warning if it stores an API key and is accessible by other users), then verifies each configured environment's endpoint and API key by making an authenticated request. A missing API key (e.g. for a local self-hosted instance) is reported as a warning. Exits with a non-zero status if any check fails.",
+	Example: `
+warrant doctor
+warrant doctor -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report := newDoctorReport()
+		verifyConfigFile(report, cmdConfig)
+
+		names := sortedEnvNames(cmdConfig)
+		if len(names) == 0 {
+			report.add("config", checkFailed, "no environments configured. Run 'warrant init'")
+		}
+		for _, name := range names {
+			verifyEnvironment(report, name, cmdConfig.Environments[name])
+		}
+
+		printDoctorReport(report)
+		return nil
+	},
+}
+
+var verifyEnvCmd = &cobra.Command{
+	Use:   "verify [envName]",
+	Short: "Verify an environment's endpoint and API key",
+	Long:  "Verify an environment's (defaults to the active environment) endpoint and API key by making an authenticated request. Reports latency and TLS details. A missing API key (e.g. for a local self-hosted instance) is reported as a warning. Exits with a non-zero status if verification fails.",
+	Example: `
+warrant env verify
+warrant env verify prod`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Not GetConfigOrExit, so a broken active environment doesn't prevent verifying another one
+		name := envName
+		if len(args) == 1 {
+			name = args[0]
+		}
+		report := newDoctorReport()
+		if name == "" {
+			report.add("config", checkFailed, "no active environment configured. Run 'warrant init'")
+		} else if env, ok := cmdConfig.Environments[name]; !ok {
+			report.add(name, checkFailed, fmt.Sprintf("environment '%s' does not exist", name))
+		} else {
+			verifyEnvironment(report, name, env)
+		}
+
+		printDoctorReport(report)
+		return nil
+	},
+}
+
+const (
+	checkPassed  = "passed"
+	checkFailed  = "failed"
+	checkWarning = "warning"
+)
+
+// The result of a single check, for the config file or an environment (its scope)
+type doctorCheck struct {
+	Scope   string `json:"scope"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// The results of the checks run by 'doctor' or 'env verify', e.g. for --output json
+type doctorReport struct {
+	Passed bool          `json:"passed"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+func newDoctorReport() *doctorReport {
+	return &doctorReport{
+		Passed: true,
+		Checks: []doctorCheck{},
+	}
+}
+
+// One row per check for table and csv output
+func (r *doctorReport) TableRows() any {
+	return r.Checks
+}
+
+func (r *doctorReport) add(scope string, status string, msg string) {
+	r.Checks = append(r.Checks, doctorCheck{
+		Scope:   scope,
+		Status:  status,
+		Message: msg,
+	})
+	if status == checkFailed {
+		r.Passed = false
+	}
+}
+
+// Print the checks grouped by scope (or in the selected output format), exiting with ExitFailure if any failed
+func printDoctorReport(report *doctorReport) {
+	printer.PrintResult(report, func() {
+		for i, check := range report.Checks {
+			if i == 0 || check.Scope != report.Checks[i-1].Scope {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Println(printer.Style(check.Scope).Bold())
+			}
+			printCheck(check)
+		}
+	})
+	if !report.Passed {
+		os.Exit(printer.ExitFailure)
+	}
+}
+
+func verifyConfigFile(report *doctorReport, cfg *config.Config) {
+	if cfg.IsEphemeral() {
+		report.add("config", checkPassed, "using WARRANT_API_KEY (no config file)")
+		return
+	}
+
+	_, err := os.Stat(cfg.Path())
+	if err != nil {
+		report.add("config", checkFailed, fmt.Sprintf("config file %s: %s", cfg.Path(), err.Error()))
+		return
+	}
+	report.add("config", checkPassed, fmt.Sprintf("config file %s", cfg.Path()))
+
+	if mode, insecure := cfg.InsecurePermissions(); insecure {
+		report.add("config", checkWarning, fmt.Sprintf("config file stores an API key and is accessible by other users (mode %#o). Run 'chmod 600 %s'", mode, cfg.Path()))
+		return
+	}
+	report.add("config", checkPassed, "config file permissions")
+}
+
+// Run checks against a single environment, adding the result of each to report
+func verifyEnvironment(report *doctorReport, name string, env config.Environment) {
+	err := env.Validate()
+	if err != nil {
+		report.add(name, checkFailed, err.Error())
+		return
+	}
+	report.add(name, checkPassed, fmt.Sprintf("endpoint %s", env.ApiEndpoint))
+
+	apiKey, err := env.ResolveApiKey()
+	if err != nil {
+		report.add(name, checkFailed, fmt.Sprintf("api key: %s", err.Error()))
+		return
+	}
+	if apiKey == "" {
+		// Self-hosted instances often run without an API key, so still make the request
+		report.add(name, checkWarning, "api key: not configured")
+	} else {
+		report.add(name, checkPassed, "api key resolved")
+	}
+
+	requestTimeout := timeout
+	if requestTimeout <= 0 {
+		requestTimeout = defaultVerifyTimeout
+	}
+	transport := &tlsRecordingTransport{base: http.DefaultTransport}
+	client := objecttype.NewClient(warrant.ClientConfig{
+		ApiKey:      apiKey,
+		ApiEndpoint: env.ApiEndpoint,
+		HttpClient: &http.Client{
+			Transport: transport,
+			Timeout:   requestTimeout,
+		},
+	})
+	start := time.Now()
+	_, err = client.ListObjectTypes(&warrant.ListObjectTypeParams{
+		ListParams: warrant.ListParams{
+			Limit: 1,
+		},
+	})
+	latency := time.Since(start).Round(time.Millisecond)
+	if transport.tls != nil {
+		report.add(name, checkPassed, fmt.Sprintf("tls %s", describeTls(transport.tls)))
+	}
+	if err != nil {
+		report.add(name, checkFailed, fmt.Sprintf("request failed after %s: %s", latency, err.Error()))
+		return
+	}
+	report.add(name, checkPassed, fmt.Sprintf("request succeeded (%s)", latency))
+}
+
+func printCheck(check doctorCheck) {
+	fmt.Printf("  %s %s\n", checkStatusLabel(check.Status), check.Message)
+}
+
+// A symbol for the status of a check, or a plain label (e.g. "fail") if output isn't decorated
+func checkStatusLabel(status string) string {
+	if !printer.IsDecorated() {
+		switch status {
+		case checkPassed:
+			return "ok  "
+		case checkWarning:
+			return "warn"
+		default:
+			return "fail"
+		}
+	}
+	switch status {
+	case checkPassed:
+		return printer.Style(printer.Checkmark).Foreground(printer.Green).String()
+	case checkWarning:
+		return printer.Style(printer.Warning).Foreground(printer.Yellow).String()
+	default:
+		return printer.Style(printer.Cross).Foreground(printer.Red).String()
+	}
+}
+
+func describeTls(state *tls.ConnectionState) string {
+	s := fmt.Sprintf("%s, %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		s = fmt.Sprintf("%s, certificate %s issued by %s, expires %s", s, cert.Subject.CommonName, cert.Issuer.CommonName, cert.NotAfter.Format(time.DateOnly))
+	}
+	return s
+}
+
+// Records the TLS connection state of the last response so it can be reported
+type tlsRecordingTransport struct {
+	base http.RoundTripper
+	tls  *tls.ConnectionState
+}
+
+func (t *tlsRecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil && resp.TLS != nil {
+		t.tls = resp.TLS
+	}
+	return resp, err
+}
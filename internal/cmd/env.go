@@ -15,23 +15,36 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"os"
 	"sort"
+	"strings"
 
-	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
+	"github.com/warrant-dev/warrant-cli/internal/config"
 	"github.com/warrant-dev/warrant-cli/internal/printer"
 	"github.com/warrant-dev/warrant-cli/internal/reader"
 )
 
 var listEnvs bool
+var revealApiKey bool
 
 func init() {
 	envCmd.Flags().BoolVarP(&listEnvs, "list", "l", false, "list all configured environments")
+	showEnvCmd.Flags().BoolVar(&revealApiKey, "reveal", false, "show the API key instead of masking it")
+
+	addNewEnvFlags(addEnvCmd)
 
 	envCmd.AddCommand(addEnvCmd)
 	envCmd.AddCommand(removeEnvCmd)
 	envCmd.AddCommand(switchEnvCmd)
+	envCmd.AddCommand(showEnvCmd)
+	envCmd.AddCommand(renameEnvCmd)
+	envCmd.AddCommand(copyEnvCmd)
+	envCmd.AddCommand(setEnvCmd)
+	envCmd.AddCommand(editEnvCmd)
 
 	rootCmd.AddCommand(envCmd)
 }
@@ -39,60 +52,132 @@ func init() {
 var envCmd = &cobra.Command{
 	Use:   "env",
 	Short: "List configured environment(s)",
-	Long:  "List configured environment(s), including the current active environment.",
+	Long:  "List configured environment(s), including the current active environment and the config file they were loaded from. The active environment can be overridden for a single command via the --env flag or the WARRANT_ENV environment variable.",
 	Example: `
 warrant env
-warrant env --list`,
+warrant env --list
+WARRANT_ENV=prod warrant env`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		config := GetConfigOrExit()
 
 		if listEnvs {
-			if len(config.Environments) == 1 {
-				fmt.Println(config.ActiveEnvironment)
-				return nil
+			var envs []envSummary
+			for _, env := range sortedEnvNames(config) {
+				envs = append(envs, newEnvSummary(config, env))
 			}
+			printer.PrintResult(envs, func() {
+				if len(envs) == 1 {
+					fmt.Println(envName)
+					printConfigPath(config)
+					return
+				}
 
-			envs := make([]string, 0, len(config.Environments))
-			for k := range config.Environments {
-				envs = append(envs, k)
-			}
-			sort.Strings(envs)
-			for _, env := range envs {
-				if env == config.ActiveEnvironment {
-					fmt.Println(termenv.String("* " + env).Bold())
-				} else {
-					fmt.Println("  " + env)
+				for _, env := range envs {
+					label := env.Name
+					if env.Protected {
+						label += " (protected)"
+					}
+					if env.Active {
+						fmt.Println(printer.Style("* " + label).Bold())
+					} else {
+						fmt.Println("  " + label)
+					}
 				}
-			}
+				printConfigPath(config)
+			})
 
 			return nil
 		}
 
-		fmt.Println(config.ActiveEnvironment)
+		printer.PrintResult(newEnvSummary(config, envName), func() {
+			fmt.Println(envName)
+			printConfigPath(config)
+		})
 		return nil
 	},
 }
 
+// Summary of an environment, as output by 'env', 'env --list' and the commands that change environments
+type envSummary struct {
+	Name        string `json:"name"`
+	Active      bool   `json:"active"`
+	ApiEndpoint string `json:"apiEndpoint"`
+	Protected   bool   `json:"protected"`
+	ReadOnly    bool   `json:"readOnly"`
+	Config      string `json:"config"`
+}
+
+func newEnvSummary(config *config.Config, name string) envSummary {
+	env := config.Environments[name]
+	return envSummary{
+		Name:        name,
+		Active:      name == envName,
+		ApiEndpoint: env.ApiEndpoint,
+		Protected:   env.Protected,
+		ReadOnly:    env.ReadOnly,
+		Config:      config.Path(),
+	}
+}
+
+// Make name the active environment. Unless another environment was selected (via --env or WARRANT_ENV), it's
+// also the environment the rest of the command runs against.
+func setActiveEnvironment(name string) {
+	if envName == cmdConfig.ActiveEnvironment {
+		envName = name
+	}
+	cmdConfig.ActiveEnvironment = name
+}
+
+func sortedEnvNames(config *config.Config) []string {
+	envs := make([]string, 0, len(config.Environments))
+	for k := range config.Environments {
+		envs = append(envs, k)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+func printConfigPath(config *config.Config) {
+	if config.IsEphemeral() {
+		fmt.Println(printer.Style("config: none (using WARRANT_API_KEY)").Faint())
+		return
+	}
+	fmt.Println(printer.Style("config: " + config.Path()).Faint())
+	if mode, insecure := config.InsecurePermissions(); insecure {
+		fmt.Fprintf(os.Stderr, "Warning: config file %s stores an API key and is accessible by other users (mode %#o). Run 'chmod 600 %s' to restrict access.\n", config.Path(), mode, config.Path())
+	}
+}
+
 var addEnvCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new environment to config",
-	Long:  "Add a new environment to config, including its API key and API endpoint.",
+	Long:  "Add a new environment to config, including its API key and API endpoint. If no environment is active yet (e.g. the config file doesn't exist), the new environment becomes the active environment. Prompts are skipped for values provided via flags.",
 	Example: `
-warrant add`,
+warrant env add
+warrant env add --name staging --endpoint https://api.warrant.dev
+echo $STAGING_API_KEY | warrant env add --name staging --api-key-stdin`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		config := GetConfigOrExit()
-		envToAdd, newEnv, err := reader.ReadEnvFromConsole()
+		// Not GetConfigOrExit, so environments can also be added to an empty (or not yet created) config
+		envToAdd, newEnv, err := reader.ReadEnv(newEnvName, newEnvEndpoint, newEnvApiKeyStdin)
 		if err != nil {
 			return err
 		}
-		config.Environments[envToAdd] = *newEnv
-		err = config.Write()
+		if cmdConfig.Environments == nil {
+			cmdConfig.Environments = make(map[string]config.Environment)
+		}
+		cmdConfig.Environments[envToAdd] = *newEnv
+		if cmdConfig.ActiveEnvironment == "" {
+			setActiveEnvironment(envToAdd)
+		}
+		err = cmdConfig.Write()
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Added environment '%s'\n", envToAdd)
+		printer.PrintChange(newEnvSummary(cmdConfig, envToAdd), func() {
+			fmt.Printf("Added environment '%s'\n", envToAdd)
+		})
 
 		return nil
 	},
@@ -103,23 +188,26 @@ var removeEnvCmd = &cobra.Command{
 	Short: "Remove an existing environment from config",
 	Long:  "Remove an existing environment from config, provided it exists and is not currently active.",
 	Example: `
-warrant remove test`,
+warrant env remove test`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		config := GetConfigOrExit()
 		envToRemove := args[0]
 		if envToRemove == config.ActiveEnvironment {
-			printer.PrintErrAndExit("cannot remove active environment")
+			exitWithConfigError(fmt.Sprintf("cannot remove active environment '%s'. Switch to another environment first", envToRemove))
 		}
 		if _, ok := config.Environments[envToRemove]; !ok {
-			printer.PrintErrAndExit(fmt.Sprintf("environment '%s' does not exist", envToRemove))
+			exitWithConfigError(fmt.Sprintf("environment '%s' does not exist", envToRemove))
 		}
+		removed := newEnvSummary(config, envToRemove)
 		delete(config.Environments, envToRemove)
 		err := config.Write()
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Removed environment '%s'\n", envToRemove)
+		printer.PrintChange(removed, func() {
+			fmt.Printf("Removed environment '%s'\n", envToRemove)
+		})
 
 		return nil
 	},
@@ -130,21 +218,230 @@ var switchEnvCmd = &cobra.Command{
 	Short: "Switch to a given environment",
 	Long:  "Switch to a given environment, provided it exists in config.",
 	Example: `
-warrant switch prod`,
+warrant env switch prod`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		config := GetConfigOrExit()
 		env := args[0]
 		if _, ok := config.Environments[env]; !ok {
-			printer.PrintErrAndExit(fmt.Sprintf("environment '%s' does not exist", env))
+			exitWithConfigError(fmt.Sprintf("environment '%s' does not exist", env))
+		}
+		setActiveEnvironment(env)
+		err := config.Write()
+		if err != nil {
+			return err
+		}
+		printer.PrintChange(newEnvSummary(config, env), func() {
+			fmt.Printf("Switched to environment '%s'\n", env)
+		})
+
+		return nil
+	},
+}
+
+var showEnvCmd = &cobra.Command{
+	Use:   "show [envName]",
+	Short: "Show an environment's configuration",
+	Long:  "Show an environment's configuration (defaults to the active environment). The API key is masked unless --reveal is provided.",
+	Example: `
+warrant env show
+warrant env show prod
+warrant env show prod --reveal`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := GetConfigOrExit()
+		name := envName
+		if len(args) == 1 {
+			name = args[0]
+		}
+		env, ok := config.Environments[name]
+		if !ok {
+			exitWithConfigError(fmt.Sprintf("environment '%s' does not exist", name))
+		}
+		if !revealApiKey {
+			env = env.Masked()
+		}
+
+		printer.PrintResult(env, func() {
+			fmt.Println(name)
+			printer.PrintJson(env)
+		})
+
+		return nil
+	},
+}
+
+var renameEnvCmd = &cobra.Command{
+	Use:   "rename <envName> <newEnvName>",
+	Short: "Rename an existing environment",
+	Long:  "Rename an existing environment. If the environment is currently active, the new name becomes the active environment.",
+	Example: `
+warrant env rename dev staging`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := GetConfigOrExit()
+		oldName, newName := args[0], args[1]
+		env, ok := config.Environments[oldName]
+		if !ok {
+			exitWithConfigError(fmt.Sprintf("environment '%s' does not exist", oldName))
+		}
+		if _, ok := config.Environments[newName]; ok {
+			exitWithConfigError(fmt.Sprintf("environment '%s' already exists", newName))
+		}
+		delete(config.Environments, oldName)
+		config.Environments[newName] = env
+		if config.ActiveEnvironment == oldName {
+			setActiveEnvironment(newName)
 		}
-		config.ActiveEnvironment = env
 		err := config.Write()
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Switched to environment '%s'\n", env)
+		printer.PrintChange(newEnvSummary(config, newName), func() {
+			fmt.Printf("Renamed environment '%s' to '%s'\n", oldName, newName)
+		})
 
 		return nil
 	},
 }
+
+var copyEnvCmd = &cobra.Command{
+	Use:   "copy <envName> <newEnvName>",
+	Short: "Copy an existing environment to a new environment",
+	Long:  "Copy an existing environment, including its API key and endpoint, to a new environment.",
+	Example: `
+warrant env copy prod prod-readonly`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := GetConfigOrExit()
+		srcName, dstName := args[0], args[1]
+		env, ok := config.Environments[srcName]
+		if !ok {
+			exitWithConfigError(fmt.Sprintf("environment '%s' does not exist", srcName))
+		}
+		if _, ok := config.Environments[dstName]; ok {
+			exitWithConfigError(fmt.Sprintf("environment '%s' already exists", dstName))
+		}
+		// Round-trip through json so the copy doesn't share any nested values with the source
+		copied, err := copyEnvironment(env)
+		if err != nil {
+			return err
+		}
+		config.Environments[dstName] = copied
+		err = config.Write()
+		if err != nil {
+			return err
+		}
+		printer.PrintChange(newEnvSummary(config, dstName), func() {
+			fmt.Printf("Copied environment '%s' to '%s'\n", srcName, dstName)
+		})
+
+		return nil
+	},
+}
+
+var setEnvCmd = &cobra.Command{
+	Use:   "set <envName> <field=value>...",
+	Short: "Set one or more fields of an existing environment",
+	Long:  "Set one or more fields (e.g. apiEndpoint, apiKey, apiKeySource) of an existing environment. 'endpoint', 'key' and 'source' can be used as shorthand. An empty value unsets the field.",
+	Example: `
+warrant env set prod endpoint=https://api.warrant.dev
+warrant env set local apiKeySource=env:WARRANT_LOCAL_API_KEY apiKey=`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := GetConfigOrExit()
+		name := args[0]
+		env, ok := config.Environments[name]
+		if !ok {
+			exitWithConfigError(fmt.Sprintf("environment '%s' does not exist", name))
+		}
+		for _, arg := range args[1:] {
+			field, value, found := strings.Cut(arg, "=")
+			if !found || field == "" {
+				exitWithUsageError(fmt.Sprintf("invalid field '%s', must be provided as field=value", arg))
+			}
+			var err error
+			env, err = env.SetField(field, value)
+			if err != nil {
+				return err
+			}
+		}
+		err := env.Validate()
+		if err != nil {
+			return err
+		}
+		config.Environments[name] = env
+		err = config.Write()
+		if err != nil {
+			return err
+		}
+		printer.PrintChange(newEnvSummary(config, name), func() {
+			fmt.Printf("Updated environment '%s'\n", name)
+		})
+
+		return nil
+	},
+}
+
+var editEnvCmd = &cobra.Command{
+	Use:   "edit [envName]",
+	Short: "Edit an environment's configuration in $EDITOR",
+	Long:  "Edit an environment's configuration (defaults to the active environment) as json in $EDITOR. Changes are validated before being saved.",
+	Example: `
+warrant env edit
+EDITOR=nano warrant env edit prod`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := GetConfigOrExit()
+		name := envName
+		if len(args) == 1 {
+			name = args[0]
+		}
+		env, ok := cfg.Environments[name]
+		if !ok {
+			exitWithConfigError(fmt.Sprintf("environment '%s' does not exist", name))
+		}
+
+		contents, err := json.MarshalIndent(env, "", "    ")
+		if err != nil {
+			return err
+		}
+		edited, err := reader.EditInEditor(append(contents, '\n'), "warrant-env-*.json")
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(bytes.TrimSpace(edited), contents) {
+			printer.PrintChange(newEnvSummary(cfg, name), func() {
+				fmt.Println("No changes made")
+			})
+			return nil
+		}
+
+		updatedEnv, err := config.ParseEnvironment(edited, env)
+		if err != nil {
+			return err
+		}
+		err = updatedEnv.Validate()
+		if err != nil {
+			return err
+		}
+		cfg.Environments[name] = updatedEnv
+		err = cfg.Write()
+		if err != nil {
+			return err
+		}
+		printer.PrintChange(newEnvSummary(cfg, name), func() {
+			fmt.Printf("Updated environment '%s'\n", name)
+		})
+
+		return nil
+	},
+}
+
+func copyEnvironment(env config.Environment) (config.Environment, error) {
+	contents, err := json.Marshal(env)
+	if err != nil {
+		return env, err
+	}
+	return config.ParseEnvironment(contents, env)
+}
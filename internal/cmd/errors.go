@@ -0,0 +1,174 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/warrant-dev/warrant-cli/internal/printer"
+	"github.com/warrant-dev/warrant-go/v6"
+)
+
+// SDK errors for unsuccessful responses have messages of the form "HTTP <status> <body>"
+var apiErrorRegexp = regexp.MustCompile(`(?s)^HTTP (\d{3}) ?(.*)$`)
+
+// Convert an error returned by a command into a printer.Error with the appropriate exit code
+func toCliError(err error) *printer.Error {
+	var cliErr *printer.Error
+	if errors.As(err, &cliErr) {
+		return cliErr
+	}
+
+	var sdkErr warrant.Error
+	if errors.As(err, &sdkErr) {
+		cliErr = parseApiError(sdkErr)
+		addHints(cliErr)
+		return cliErr
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return printer.NewError(printer.ExitValidation, err)
+	}
+
+	return printer.NewError(printer.ExitFailure, err)
+}
+
+func parseApiError(sdkErr warrant.Error) *printer.Error {
+	if sdkErr.WrappedError != nil {
+		var netErr net.Error
+		if errors.As(sdkErr.WrappedError, &netErr) {
+			return printer.NewError(printer.ExitNetwork, fmt.Errorf("unable to reach %s: %w", warrant.ApiEndpoint, netErr))
+		}
+		return printer.NewError(printer.ExitFailure, fmt.Errorf("%s: %w", sdkErr.Message, sdkErr.WrappedError))
+	}
+
+	matches := apiErrorRegexp.FindStringSubmatch(sdkErr.Message)
+	if matches == nil {
+		return printer.NewError(printer.ExitFailure, errors.New(sdkErr.Message))
+	}
+	status, _ := strconv.Atoi(matches[1])
+	body := strings.TrimSpace(matches[2])
+
+	cliErr := printer.NewError(exitCodeForStatus(status), sdkErr)
+	cliErr.Status = status
+	cliErr.Message = body
+	var apiErr struct {
+		Code      interface{} `json:"code"`
+		Message   string      `json:"message"`
+		RequestId string      `json:"requestId"`
+		TraceId   string      `json:"traceId"`
+	}
+	if json.Unmarshal([]byte(body), &apiErr) == nil {
+		if apiErr.Message != "" {
+			cliErr.Message = apiErr.Message
+		}
+		if code, ok := apiErr.Code.(string); ok {
+			cliErr.Code = code
+		}
+		cliErr.RequestId = apiErr.RequestId
+		if cliErr.RequestId == "" {
+			cliErr.RequestId = apiErr.TraceId
+		}
+	}
+	if cliErr.RequestId == "" {
+		cliErr.RequestId = apiTransport.lastRequestId()
+	}
+	if cliErr.Message == "" {
+		cliErr.Message = http.StatusText(status)
+	}
+	return cliErr
+}
+
+// Records the request id of the last unsuccessful API response so it can be included in errors
+type requestIdTransport struct {
+	base      http.RoundTripper
+	mu        sync.Mutex
+	requestId string
+}
+
+var apiTransport = &requestIdTransport{base: http.DefaultTransport}
+
+func (t *requestIdTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil && resp.StatusCode >= 400 {
+		requestId := resp.Header.Get("X-Request-Id")
+		if requestId == "" {
+			requestId = resp.Header.Get("X-Trace-Id")
+		}
+		t.mu.Lock()
+		t.requestId = requestId
+		t.mu.Unlock()
+	}
+	return resp, err
+}
+
+func (t *requestIdTransport) lastRequestId() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.requestId
+}
+
+func exitCodeForStatus(status int) int {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return printer.ExitAuth
+	case status == http.StatusNotFound:
+		return printer.ExitNotFound
+	case status == http.StatusTooManyRequests || status >= 500:
+		return printer.ExitServer
+	case status >= 400:
+		return printer.ExitValidation
+	default:
+		return printer.ExitFailure
+	}
+}
+
+// Report flag and argument errors of cmd and its subcommands as usage errors
+func wrapUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return printer.UsageError(err)
+	})
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(c *cobra.Command, a []string) error {
+			err := args(c, a)
+			if err != nil {
+				return printer.UsageError(err)
+			}
+			return nil
+		}
+	}
+	for _, subCmd := range cmd.Commands() {
+		wrapUsageErrors(subCmd)
+	}
+}
+
+func exitWithUsageError(msg string) {
+	printer.ExitWithError(printer.UsageError(errors.New(msg)))
+}
+
+func exitWithConfigError(msg string) {
+	printer.ExitWithError(printer.ConfigError(errors.New(msg)))
+}
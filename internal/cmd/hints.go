@@ -0,0 +1,182 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/warrant-dev/warrant-cli/internal/printer"
+	"github.com/warrant-dev/warrant-go/v6"
+	"github.com/warrant-dev/warrant-go/v6/objecttype"
+)
+
+var objectTypeNotFoundRegexp = regexp.MustCompile(`(?i)^object ?type '?([^'\s]+)'? not found`)
+
+// How long cached object types are used for suggestions before being fetched again
+var objectTypeCacheTtl = time.Hour
+
+// Add hints on how to resolve an API error (e.g. suggest a similar object type if one wasn't found)
+func addHints(cliErr *printer.Error) {
+	switch {
+	case cliErr.ExitCode == printer.ExitNetwork:
+		cliErr.Hints = append(cliErr.Hints, fmt.Sprintf("check the API endpoint for environment '%s' (%s), or run 'warrant doctor'", envName, warrant.ApiEndpoint))
+	case cliErr.Status == http.StatusUnauthorized || cliErr.Status == http.StatusForbidden:
+		cliErr.Hints = append(cliErr.Hints, fmt.Sprintf("check your API key for environment '%s' (run 'warrant env verify %s')", envName, envName))
+	case cliErr.Status == http.StatusNotFound:
+		matches := objectTypeNotFoundRegexp.FindStringSubmatch(cliErr.Message)
+		if matches == nil {
+			return
+		}
+		if suggestion := suggestObjectType(matches[1]); suggestion != "" {
+			cliErr.Hints = append(cliErr.Hints, fmt.Sprintf("object type '%s' not found; did you mean '%s'?", matches[1], suggestion))
+		} else {
+			cliErr.Hints = append(cliErr.Hints, "run 'warrant objecttype list' to see available object types")
+		}
+	case cliErr.Status == http.StatusTooManyRequests:
+		cliErr.Hints = append(cliErr.Hints, "rate limited by the Warrant API, retry after a short delay")
+	case cliErr.Status >= 500:
+		cliErr.Hints = append(cliErr.Hints, "the Warrant API may be temporarily unavailable, retry later")
+	}
+}
+
+// The known object type most similar to objectType, if any is similar enough
+func suggestObjectType(objectType string) string {
+	suggestion := ""
+	bestDistance := len(objectType)/3 + 2
+	for _, candidate := range cachedObjectTypes() {
+		distance := editDistance(objectType, candidate)
+		if distance > 0 && distance < bestDistance {
+			suggestion = candidate
+			bestDistance = distance
+		}
+	}
+	return suggestion
+}
+
+type objectTypeCache struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	Types     []string  `json:"types"`
+}
+
+// Location of the current environment's cached object types, keyed by its name and API endpoint (so environments
+// with the same name in different configs don't share a cache). There's no cache with WARRANT_API_KEY, which
+// doesn't write anything to disk.
+func objectTypeCachePath() (string, bool) {
+	if cmdConfig == nil || cmdConfig.IsEphemeral() {
+		return "", false
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(warrant.ApiEndpoint))
+	name := fmt.Sprintf("%s-%s.json", url.PathEscape(envName), hex.EncodeToString(sum[:6]))
+	return filepath.Join(cacheDir, "warrant", "objecttypes", name), true
+}
+
+// Object types of the current environment, from the cache if it's recent enough. Returns
+// a stale (or empty) list if they can't be fetched.
+func cachedObjectTypes() []string {
+	var cache objectTypeCache
+	path, ok := objectTypeCachePath()
+	if ok {
+		contents, err := os.ReadFile(path)
+		if err == nil {
+			_ = json.Unmarshal(contents, &cache)
+		}
+	}
+	if time.Since(cache.UpdatedAt) < objectTypeCacheTtl {
+		return cache.Types
+	}
+
+	client := objecttype.NewClient(warrant.ClientConfig{
+		ApiKey:      warrant.ApiKey,
+		ApiEndpoint: warrant.ApiEndpoint,
+		HttpClient:  &http.Client{Timeout: 5 * time.Second},
+	})
+	listParams := &warrant.ListObjectTypeParams{}
+	var types []warrant.ObjectType
+	for {
+		typesResp, err := client.ListObjectTypes(listParams)
+		if err != nil {
+			return cache.Types
+		}
+		types = append(types, typesResp.Results...)
+		if typesResp.NextCursor == "" {
+			break
+		}
+		listParams.NextCursor = typesResp.NextCursor
+	}
+	saveObjectTypeCache(types)
+
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, t.Type)
+	}
+	return names
+}
+
+// Cache the current environment's object types for suggestions. Failures are ignored.
+func saveObjectTypeCache(types []warrant.ObjectType) {
+	path, ok := objectTypeCachePath()
+	if !ok {
+		return
+	}
+	cache := objectTypeCache{
+		UpdatedAt: time.Now(),
+		Types:     make([]string, 0, len(types)),
+	}
+	for _, t := range types {
+		cache.Types = append(cache.Types, t.Type)
+	}
+	contents, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0700) != nil {
+		return
+	}
+	_ = os.WriteFile(path, contents, 0600)
+}
+
+// Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
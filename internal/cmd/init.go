@@ -19,38 +19,57 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/warrant-dev/warrant-cli/internal/config"
+	"github.com/warrant-dev/warrant-cli/internal/printer"
 	"github.com/warrant-dev/warrant-cli/internal/reader"
 )
 
+var newEnvName string
+var newEnvEndpoint string
+var newEnvApiKeyStdin bool
+
 func init() {
+	addNewEnvFlags(initCmd)
+
 	rootCmd.AddCommand(initCmd)
 }
 
+// Flags for providing a new environment non-interactively (shared by 'init' and 'env add')
+func addNewEnvFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&newEnvName, "name", "", "name of the environment (skips the prompt)")
+	cmd.Flags().StringVar(&newEnvEndpoint, "endpoint", "", "API endpoint of the environment (skips the prompt, defaults to "+config.DefaultApiEndpoint+")")
+	cmd.Flags().BoolVar(&newEnvApiKeyStdin, "api-key-stdin", false, "read the API key from stdin instead of prompting (requires --name)")
+}
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize the CLI for use",
-	Long:  "Initialize the CLI for use, including configuring an environment and API key.",
+	Long:  "Initialize the CLI for use, including configuring an environment and API key. The new environment is added to any existing config and becomes the active environment. Prompts are skipped for values provided via flags.",
 	Example: `
-warrant init`,
+warrant init
+warrant init --name prod --endpoint https://api.warrant.dev
+op read op://warrant/prod/api-key | warrant init --name prod --api-key-stdin`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		envName, env, err := reader.ReadEnvFromConsole()
+		name, env, err := reader.ReadEnv(newEnvName, newEnvEndpoint, newEnvApiKeyStdin)
 		if err != nil {
 			return err
 		}
 
-		fmt.Println("creating ~/.warrant.json")
-		envMap := make(map[string]config.Environment)
-		envMap[envName] = *env
-		newConfig := config.Config{
-			ActiveEnvironment: envName,
-			Environments:      envMap,
+		action := "updating"
+		if len(cmdConfig.Environments) == 0 {
+			action = "creating"
+			cmdConfig.Environments = make(map[string]config.Environment)
 		}
-		err = newConfig.Write()
+		cmdConfig.Environments[name] = *env
+		setActiveEnvironment(name)
+		err = cmdConfig.Write()
 		if err != nil {
 			return err
 		}
-		fmt.Println("setup complete")
+		printer.PrintChange(newEnvSummary(cmdConfig, name), func() {
+			fmt.Printf("%s %s\n", action, cmdConfig.Path())
+			fmt.Println("setup complete")
+		})
 
 		return nil
 	},
@@ -15,7 +15,10 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -25,25 +28,162 @@ import (
 	"github.com/warrant-dev/warrant-go/v6/object"
 )
 
+var listObjectType string
+var listObjectSearch string
+var listObjectLimit int
+var listObjectAll bool
+var listObjectSortBy string
+var listObjectSortOrder string
+var listObjectNextCursor string
+var listObjectWarrantToken string
+var getWithWarrants bool
+var getDepth int
+var updateSet []string
+var updateUnset []string
+var updateMerge string
+
 func init() {
+	listObjectCmd.Flags().StringVarP(&listObjectType, "type", "t", "", "only list objects of this type")
+	listObjectCmd.Flags().StringVar(&listObjectSearch, "q", "", "only list objects whose id or meta matches this search string")
+	listObjectCmd.Flags().IntVarP(&listObjectLimit, "limit", "l", 0, "optional number of objects to list per page")
+	listObjectCmd.Flags().BoolVar(&listObjectAll, "all", false, "fetch all objects, following nextCursor until there are no more pages")
+	listObjectCmd.Flags().StringVar(&listObjectSortBy, "sort-by", "", "field to sort objects by: objectType, objectId or createdAt")
+	listObjectCmd.Flags().StringVar(&listObjectSortOrder, "sort-order", "", "sort order: ASC or DESC")
+	listObjectCmd.Flags().StringVar(&listObjectNextCursor, "nextCursor", "", "optional nextCursor string for pagination")
+	listObjectCmd.Flags().StringVarP(&listObjectWarrantToken, "warrant-token", "w", "", "optional warrant token header value to include in list objects request")
+
+	getCmd.Flags().BoolVar(&getWithWarrants, "with-warrants", false, "also list the object's inbound and outbound warrants, grouped by relation")
+	getCmd.Flags().IntVar(&getDepth, "depth", 0, "number of levels of inbound userset subjects to follow to their members (with --with-warrants)")
+
+	updateCmd.Flags().StringArrayVar(&updateSet, "set", nil, "set a (dotted) meta key to a value, as key=value (repeatable)")
+	updateCmd.Flags().StringArrayVar(&updateUnset, "unset", nil, "remove a (dotted) meta key (repeatable)")
+	updateCmd.Flags().StringVar(&updateMerge, "merge", "", "JSON Merge Patch (RFC 7386) to apply to the object's meta")
+
+	addBatchFlags(importObjectCmd)
+	addBatchFlags(deleteCmd)
+
+	objectCmd.AddCommand(listObjectCmd)
+	objectCmd.AddCommand(importObjectCmd)
 	objectCmd.AddCommand(createCmd)
 	objectCmd.AddCommand(getCmd)
 	objectCmd.AddCommand(updateCmd)
+	objectCmd.AddCommand(editObjectCmd)
 	objectCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(objectCmd)
 }
 
 var objectCmd = &cobra.Command{
 	Use:   "object",
-	Short: "Operate on objects (create, get, update, delete)",
-	Long:  "Operate on objects (create, get, update, delete), including their metadata.",
+	Short: "Operate on objects (list, create, get, update, edit, delete)",
+	Long:  "Operate on objects (list, create, get, update, edit, delete), including their metadata.",
 	Example: `
+warrant object list --type role
+warrant object import -f users.csv
 warrant object create role:admin
 warrant object get role:admin
 warrant object update role:admin '{"name": "New name"}'
+warrant object update role:admin --set name="Admins" --unset legacyFlag
+warrant object edit role:admin
 warrant object delete role:admin`,
 }
 
+var listObjectCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List objects, optionally filtered by type or a search string",
+	Long:  "List objects, optionally filtered by type (--type) or a search string matched against object ids and meta (--q). Returns a single page of objects (printing the cursor for the next page, if any, to stderr) unless --all is provided.",
+	Example: `
+warrant object list
+warrant object list --type role --limit 50
+warrant object list --type user --q alice --sort-by createdAt --sort-order DESC
+warrant object list --all -o ndjson`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		GetApiConfigOrExit()
+
+		if listObjectSortBy != "" && !slices.Contains([]string{"objectType", "objectId", "createdAt"}, listObjectSortBy) {
+			return printer.UsageError(fmt.Errorf("invalid --sort-by '%s', must be one of: objectType, objectId, createdAt", listObjectSortBy))
+		}
+		sortOrder := strings.ToUpper(listObjectSortOrder)
+		if sortOrder != "" && sortOrder != "ASC" && sortOrder != "DESC" {
+			return printer.UsageError(fmt.Errorf("invalid --sort-order '%s', must be ASC or DESC", listObjectSortOrder))
+		}
+
+		listParams := &warrant.ListObjectParams{
+			ListParams: warrant.ListParams{
+				NextCursor: listObjectNextCursor,
+				SortBy:     listObjectSortBy,
+				SortOrder:  sortOrder,
+				Limit:      listObjectLimit,
+			},
+			ObjectType: listObjectType,
+			Query:      listObjectSearch,
+		}
+		if listObjectWarrantToken != "" {
+			listParams.RequestOptions = warrant.RequestOptions{
+				WarrantToken: listObjectWarrantToken,
+			}
+		}
+
+		if !listObjectAll {
+			objectsResp, err := object.ListObjects(listParams)
+			if err != nil {
+				return err
+			}
+			printer.Print(objectsResp.Results)
+			printNextCursor(objectsResp.NextCursor)
+			return nil
+		}
+
+		stream := printer.IsStreamable()
+		objects, err := fetchAllPages(listParams.NextCursor, stream, func(cursor string) (warrant.ListResponse[warrant.Object], error) {
+			listParams.NextCursor = cursor
+			return object.ListObjects(listParams)
+		})
+		if err != nil {
+			return err
+		}
+		if !stream {
+			printer.Print(objects)
+		}
+
+		return nil
+	},
+}
+
+var importObjectCmd = &cobra.Command{
+	Use:   "import -f <file>",
+	Short: "Create objects in bulk from a csv, ndjson or text file",
+	Long: `Create objects in bulk from a csv, ndjson or text file (one 'type:id', or an id with --type, per line). Objects are created in batches (--batch-size), with up to --concurrency batches in flight.
+
+Csv files must have a header row. By default, object types and ids are read from the 'objectType' and 'objectId' columns and all other columns are added to each object's meta. Use --map to read them from other columns (e.g. --map type=kind,id=user_id,meta.name=full_name). Ndjson files must have one object per line (e.g. {"objectType": "user", "objectId": "1", "meta": {...}}).
+
+Rows that fail to be created (e.g. invalid objects rejected by the API) don't abort the import. They're written to a failures file (--failures-file) and reported in the summary. Errors that would fail every row (e.g. auth, network or server errors) stop the import and exit with the corresponding exit code, after writing the rows that weren't processed to the failures file as well.`,
+	Example: `
+warrant object import -f users.csv --type user --map id=user_id,meta.email=email
+warrant object import -f objects.ndjson --batch-size 500 --concurrency 8
+cat roles.txt | warrant object import -f - --format txt`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		GetApiConfigOrExit()
+		if batchFile == "" {
+			return printer.UsageError(fmt.Errorf("required flag \"file\" not set"))
+		}
+
+		rows, err := readBatchObjects()
+		if err != nil {
+			return err
+		}
+
+		ConfirmChangeOrExit(fmt.Sprintf("import %d objects", len(rows)))
+		succeeded, failures, err := runBatches(rows, "importing", func(objects []warrant.ObjectParams) error {
+			_, err := object.BatchCreate(objects)
+			return err
+		})
+
+		return reportBatchResult("imported", len(rows), succeeded, failures, err)
+	},
+}
+
 var createCmd = &cobra.Command{
 	Use:   "create <object> [meta]",
 	Short: "Create a new object of specified type with optional id and optional meta",
@@ -54,11 +194,11 @@ warrant object create user:123
 warrant object create permission:edit-users '{"name": "Edit Users"}'`,
 	Args: cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
 
 		typeAndId := strings.Split(args[0], ":")
 		if len(typeAndId) > 2 {
-			printer.PrintErrAndExit("invalid object provided, must be 'type' or 'type:id'")
+			exitWithUsageError("invalid object provided, must be 'type' or 'type:id'")
 		}
 		objectType := typeAndId[0]
 		objectId := ""
@@ -75,6 +215,7 @@ warrant object create permission:edit-users '{"name": "Edit Users"}'`,
 			}
 		}
 
+		ConfirmChangeOrExit(fmt.Sprintf("create %s", args[0]))
 		newObj, err := object.Create(&warrant.ObjectParams{
 			ObjectType: objectType,
 			ObjectId:   objectId,
@@ -84,10 +225,12 @@ warrant object create permission:edit-users '{"name": "Edit Users"}'`,
 			return err
 		}
 
-		fmt.Printf("created %s:%s\n", newObj.ObjectType, newObj.ObjectId)
-		if len(newObj.Meta) > 0 {
-			printer.PrintJson(newObj.Meta)
-		}
+		printer.PrintChange(newObj, func() {
+			fmt.Printf("created %s:%s\n", newObj.ObjectType, newObj.ObjectId)
+			if len(newObj.Meta) > 0 {
+				printer.PrintJson(newObj.Meta)
+			}
+		})
 
 		return nil
 	},
@@ -96,12 +239,21 @@ warrant object create permission:edit-users '{"name": "Edit Users"}'`,
 var getCmd = &cobra.Command{
 	Use:   "get <object>",
 	Short: "Get an object specified by type:id",
-	Long:  "Get an object specified by type:id. Also returns the object's 'meta', if present.",
+	Long:  "Get an object specified by type:id. Also returns the object's 'meta', if present. With --with-warrants, also returns the object's inbound warrants (where it's the object) and outbound warrants (where it's the subject), grouped by relation. Use --depth N to also list the members of inbound userset subjects (e.g. tenant:acme#member), following usersets N levels deep (by default, usersets aren't followed).",
 	Example: `
-warrant object get role:123`,
+warrant object get role:123
+warrant object get role:admin --with-warrants
+warrant object get document:roadmap --with-warrants --depth 1`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
+
+		if cmd.Flags().Changed("depth") && !getWithWarrants {
+			return printer.UsageError(fmt.Errorf("--depth can only be used with --with-warrants"))
+		}
+		if getDepth < 0 {
+			return printer.UsageError(fmt.Errorf("invalid --depth %d, must be at least 0", getDepth))
+		}
 
 		objectType, objectId, err := reader.ReadObjectArg(args[0])
 		if err != nil {
@@ -113,72 +265,271 @@ warrant object get role:123`,
 			return err
 		}
 
-		fmt.Printf("%s:%s\n", obj.ObjectType, obj.ObjectId)
-		if len(obj.Meta) > 0 {
-			printer.PrintJson(obj.Meta)
+		if getWithWarrants {
+			objWithWarrants, err := getObjectWithWarrants(obj, getDepth)
+			if err != nil {
+				return err
+			}
+			printer.PrintResult(objWithWarrants, func() {
+				printObjectWithWarrants(objWithWarrants)
+			})
+			return nil
 		}
 
+		printer.PrintResult(obj, func() {
+			fmt.Printf("%s:%s\n", obj.ObjectType, obj.ObjectId)
+			if len(obj.Meta) > 0 {
+				printer.PrintJson(obj.Meta)
+			}
+		})
+
 		return nil
 	},
 }
 
 var updateCmd = &cobra.Command{
-	Use:   "update <object> <meta>",
+	Use:   "update <object> [meta]",
 	Short: "Update an object's (specified as type:id) meta",
-	Long:  "Update an object's (specified as type:id) meta. Object 'meta' must be passed as a json string. Note that an object's existing type and id cannot be updated.",
+	Long:  "Update an object's (specified as type:id) meta. Either pass the object's new 'meta' as a json string (replacing its existing meta), or modify individual fields of its existing meta via --set key=value and --unset key (keys can be dotted paths, e.g. address.city) and/or a JSON Merge Patch (RFC 7386) via --merge. Values passed to --set are parsed as json if valid, otherwise they're used as strings. Modifications are applied to the object's current meta in the order --merge, --set, --unset, and the resulting changes are printed. Note that an object's existing type and id cannot be updated.",
 	Example: `
-warrant object update role:123 '{"name": "New name"}'`,
-	Args: cobra.ExactArgs(2),
+warrant object update role:123 '{"name": "New name"}'
+warrant object update role:admin --set name="Admins" --unset legacyFlag
+warrant object update role:admin --set limits.seats=10 --set tags='["a", "b"]'
+warrant object update role:admin --merge '{"name": "Admins", "legacyFlag": null}'`,
+	Args: cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
 
 		objectType, objectId, err := reader.ReadObjectArg(args[0])
 		if err != nil {
 			return err
 		}
 
-		meta, err := reader.ReadObjectMetaArg(args[1])
+		patching := len(updateSet) > 0 || len(updateUnset) > 0 || updateMerge != ""
+		if len(args) == 2 && patching {
+			return printer.UsageError(fmt.Errorf("cannot provide both meta and --set, --unset or --merge"))
+		}
+		if len(args) == 1 && !patching {
+			return printer.UsageError(fmt.Errorf("must provide either meta or --set, --unset or --merge"))
+		}
+
+		if len(args) == 2 {
+			meta, err := reader.ReadObjectMetaArg(args[1])
+			if err != nil {
+				return err
+			}
+
+			ConfirmChangeOrExit(fmt.Sprintf("update %s:%s", objectType, objectId))
+			updatedObj, err := object.Update(objectType, objectId, &warrant.ObjectParams{
+				Meta: meta,
+			})
+			if err != nil {
+				return err
+			}
+
+			printer.PrintChange(updatedObj, func() {
+				fmt.Printf("updated %s:%s\n", updatedObj.ObjectType, updatedObj.ObjectId)
+				if len(updatedObj.Meta) > 0 {
+					printer.PrintJson(updatedObj.Meta)
+				}
+			})
+			return nil
+		}
+
+		// Before fetching the object, so read-only environments reject the update without any requests
+		ConfirmChangeOrExit(fmt.Sprintf("update %s:%s", objectType, objectId))
+		original, updated, err := patchObjectMeta(objectType, objectId)
 		if err != nil {
 			return err
 		}
+		return updateObjectMeta(objectType, objectId, original, updated)
+	},
+}
+
+// Fetch the object's current meta and apply --merge, --set and --unset to a copy of it
+func patchObjectMeta(objectType string, objectId string) (map[string]interface{}, map[string]interface{}, error) {
+	var mergePatch map[string]interface{}
+	if updateMerge != "" {
+		err := json.Unmarshal([]byte(updateMerge), &mergePatch)
+		if err != nil || mergePatch == nil {
+			return nil, nil, printer.UsageError(fmt.Errorf("invalid --merge, must be a json object"))
+		}
+	}
+	type setField struct {
+		path  []string
+		value interface{}
+	}
+	var sets []setField
+	for _, arg := range updateSet {
+		path, value, err := reader.ReadMetaSetArg(arg)
+		if err != nil {
+			return nil, nil, printer.UsageError(err)
+		}
+		sets = append(sets, setField{path, value})
+	}
+
+	existing, err := object.Get(objectType, objectId, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updated := reader.CopyMeta(existing.Meta)
+	if mergePatch != nil {
+		updated = reader.ApplyMergePatch(updated, mergePatch).(map[string]interface{})
+	}
+	for _, set := range sets {
+		err = reader.SetMetaField(updated, set.path, set.value)
+		if err != nil {
+			return nil, nil, printer.UsageError(err)
+		}
+	}
+	for _, key := range updateUnset {
+		reader.UnsetMetaField(updated, strings.Split(key, "."))
+	}
+
+	return existing.Meta, updated, nil
+}
+
+type objectUpdate struct {
+	ObjectType string           `json:"objectType"`
+	ObjectId   string           `json:"objectId"`
+	Changes    []printer.Change `json:"changes"`
+	Meta       interface{}      `json:"meta,omitempty"`
+}
 
-		updatedObj, err := object.Update(objectType, objectId, &warrant.ObjectParams{
-			Meta: meta,
+// Update an object's meta from original to updated (if anything changed) and print the changes applied. Callers
+// must call ConfirmChangeOrExit first.
+func updateObjectMeta(objectType string, objectId string, original map[string]interface{}, updated map[string]interface{}) error {
+	changes := printer.Diff(original, updated)
+	if len(changes) == 0 {
+		printer.PrintChange(objectUpdate{ObjectType: objectType, ObjectId: objectId, Changes: []printer.Change{}, Meta: original}, func() {
+			fmt.Printf("no changes to %s:%s\n", objectType, objectId)
 		})
+		return nil
+	}
+
+	updatedObj, err := object.Update(objectType, objectId, &warrant.ObjectParams{
+		Meta: updated,
+	})
+	if err != nil {
+		return err
+	}
+
+	printer.PrintChange(objectUpdate{ObjectType: updatedObj.ObjectType, ObjectId: updatedObj.ObjectId, Changes: changes, Meta: updatedObj.Meta}, func() {
+		fmt.Printf("updated %s:%s\n", updatedObj.ObjectType, updatedObj.ObjectId)
+		printer.PrintDiff(changes)
+	})
+	return nil
+}
+
+var editObjectCmd = &cobra.Command{
+	Use:   "edit <object>",
+	Short: "Edit an object's (specified as type:id) meta in $EDITOR",
+	Long:  "Edit an object's (specified as type:id) meta as json in $EDITOR. If the edited meta isn't a valid json object, the editor can be re-opened to fix it. The object is only updated if its meta changed, and the resulting changes are printed.",
+	Example: `
+warrant object edit tenant:acme
+EDITOR=nano warrant object edit role:admin`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		GetApiConfigOrExit()
+
+		objectType, objectId, err := reader.ReadObjectArg(args[0])
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("updated %s:%s\n", updatedObj.ObjectType, updatedObj.ObjectId)
-		if len(updatedObj.Meta) > 0 {
-			printer.PrintJson(updatedObj.Meta)
+		// Before fetching the object and opening the editor, so edits aren't lost to a read-only environment
+		// or a declined confirmation
+		ConfirmChangeOrExit(fmt.Sprintf("update %s:%s", objectType, objectId))
+		existing, err := object.Get(objectType, objectId, nil)
+		if err != nil {
+			return err
+		}
+		original := existing.Meta
+		if original == nil {
+			original = make(map[string]interface{})
 		}
 
-		return nil
+		contents, err := json.MarshalIndent(original, "", "    ")
+		if err != nil {
+			return err
+		}
+		contents = append(contents, '\n')
+		for {
+			contents, err = reader.EditInEditor(contents, fmt.Sprintf("warrant-%s-*.json", objectType))
+			if err != nil {
+				return err
+			}
+			updated, err := reader.ReadObjectMetaArg(string(contents))
+			if err == nil && updated == nil {
+				err = fmt.Errorf("invalid object meta: must be a json object")
+			}
+			if err == nil {
+				return updateObjectMeta(objectType, objectId, original, updated)
+			}
+
+			if !reader.IsTerminal(os.Stdin) {
+				return printer.NewError(printer.ExitValidation, err)
+			}
+			fmt.Fprintf(os.Stderr, "%s\n", printer.Style("Error: "+err.Error()).Foreground(printer.Red))
+			reopen, confirmErr := reader.Confirm("Re-open the editor to fix it?")
+			if confirmErr != nil {
+				return confirmErr
+			}
+			if !reopen {
+				return printer.NewError(printer.ExitValidation, err)
+			}
+		}
 	},
 }
 
 var deleteCmd = &cobra.Command{
-	Use:   "delete <object>",
-	Short: "Delete the object with specified type:id",
-	Long:  "Delete the object with specified type:id. The entire object, including its 'meta', will be deleted.",
+	Use:   "delete [object]",
+	Short: "Delete the object with specified type:id, or objects in bulk from a file",
+	Long:  "Delete the object with specified type:id. The entire object, including its 'meta', will be deleted. To delete objects in bulk, provide a csv, ndjson or text file (one 'type:id', or an id with --type, per line) via -f (see 'object import' for file formats). Objects are deleted in batches, and rows that fail to be deleted are written to a failures file without aborting the run.",
 	Example: `
-warrant object delete role:admin`,
-	Args: cobra.ExactArgs(1),
+warrant object delete role:admin
+warrant object delete -f ids.txt
+warrant object delete -f users.csv --type user --map id=user_id
+warrant object delete -f user-ids.txt --type user`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
+
+		if batchFile != "" {
+			if len(args) > 0 {
+				return printer.UsageError(fmt.Errorf("cannot provide both an object and --file"))
+			}
+			rows, err := readBatchObjects()
+			if err != nil {
+				return err
+			}
+
+			ConfirmChangeOrExit(fmt.Sprintf("delete %d objects", len(rows)))
+			succeeded, failures, err := runBatches(rows, "deleting", func(objects []warrant.ObjectParams) error {
+				_, err := object.BatchDelete(objects)
+				return err
+			})
+			return reportBatchResult("deleted", len(rows), succeeded, failures, err)
+		}
+		if len(args) == 0 {
+			return printer.UsageError(fmt.Errorf("must provide an object to delete or --file"))
+		}
 
 		objectType, objectId, err := reader.ReadObjectArg(args[0])
 		if err != nil {
 			return err
 		}
 
+		ConfirmChangeOrExit(fmt.Sprintf("delete %s:%s", objectType, objectId))
 		_, err = object.Delete(objectType, objectId)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("deleted %s:%s\n", objectType, objectId)
+		printer.PrintChange(warrant.Object{ObjectType: objectType, ObjectId: objectId}, func() {
+			fmt.Printf("deleted %s:%s\n", objectType, objectId)
+		})
 
 		return nil
 	},
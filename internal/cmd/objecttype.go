@@ -56,7 +56,7 @@ var listObjecttypeCmd = &cobra.Command{
 warrant objecttype list`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
 
 		listParams := &warrant.ListObjectTypeParams{}
 		if listObjecttypeWarrantToken != "" {
@@ -80,7 +80,8 @@ warrant objecttype list`,
 				listParams.NextCursor = typesResp.NextCursor
 			}
 		}
-		printer.PrintJson(types)
+		saveObjectTypeCache(types)
+		printer.Print(types)
 
 		return nil
 	},
@@ -94,7 +95,8 @@ var applyObjecttypeCmd = &cobra.Command{
 warrant objecttype apply -f types.json`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
+		ConfirmChangeOrExit("apply object types")
 
 		var bytes []byte
 		var err error
@@ -124,12 +126,14 @@ warrant objecttype apply -f types.json`,
 			return err
 		}
 
-		_, err = objecttype.BatchUpdate(objectTypes)
+		updatedTypes, err := objecttype.BatchUpdate(objectTypes)
 		if err != nil {
 			return err
 		}
 
-		fmt.Println("objecttypes updated")
+		printer.PrintChange(updatedTypes, func() {
+			fmt.Println("objecttypes updated")
+		})
 
 		return nil
 	},
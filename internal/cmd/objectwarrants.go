@@ -0,0 +1,236 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/warrant-dev/warrant-cli/internal/printer"
+	"github.com/warrant-dev/warrant-go/v6"
+)
+
+// An object along with the warrants attached to it, grouped by relation
+type objectWithWarrants struct {
+	*warrant.Object
+	Inbound  []relationWarrants `json:"inbound"`
+	Outbound []relationWarrants `json:"outbound"`
+}
+
+type relationWarrants struct {
+	Relation string            `json:"relation"`
+	Warrants []attachedWarrant `json:"warrants"`
+}
+
+// A warrant attached to an object. Inbound warrants (where the object is the object) only set Subject, outbound
+// warrants (where the object is the subject) set Object and, if the warrant is on a userset of the object, Subject.
+// Members lists the subjects of a userset subject when followed with --depth. Cycle is set instead if the userset
+// was already followed by one of the warrant's ancestors.
+type attachedWarrant struct {
+	Object  string            `json:"object,omitempty"`
+	Subject string            `json:"subject,omitempty"`
+	Policy  string            `json:"policy,omitempty"`
+	Members []attachedWarrant `json:"members,omitempty"`
+	Cycle   bool              `json:"cycle,omitempty"`
+}
+
+type objectWarrantRow struct {
+	Direction string `json:"direction"`
+	Relation  string `json:"relation"`
+	Object    string `json:"object"`
+	Subject   string `json:"subject"`
+	Policy    string `json:"policy"`
+	Via       string `json:"via"`
+}
+
+// Fetch all inbound and outbound warrants of obj. Inbound userset subjects (e.g. tenant:acme#member) are
+// followed to their members depth levels deep.
+func getObjectWithWarrants(obj *warrant.Object, depth int) (*objectWithWarrants, error) {
+	inbound, err := listInboundWarrants(obj.ObjectType, obj.ObjectId, "", depth, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	outbound, err := listAllWarrants(&warrant.ListWarrantParams{
+		SubjectType: obj.ObjectType,
+		SubjectId:   obj.ObjectId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	outboundByRelation := make(map[string][]attachedWarrant)
+	for _, w := range outbound {
+		attached := attachedWarrant{
+			Object: fmt.Sprintf("%s:%s", w.ObjectType, w.ObjectId),
+			Policy: w.Policy,
+		}
+		if w.Subject.Relation != "" {
+			attached.Subject = subjectAsString(w.Subject)
+		}
+		outboundByRelation[w.Relation] = append(outboundByRelation[w.Relation], attached)
+	}
+
+	return &objectWithWarrants{
+		Object:   obj,
+		Inbound:  groupByRelation(inbound),
+		Outbound: groupByRelation(outboundByRelation),
+	}, nil
+}
+
+// Fetch the warrants where objectType:objectId is the object (optionally only those with the given relation),
+// keyed by relation. Userset subjects are followed depth levels deep. Usersets already being followed on the current
+// path (i.e. cycles) are marked rather than followed again.
+func listInboundWarrants(objectType string, objectId string, relation string, depth int, path map[string]bool) (map[string][]attachedWarrant, error) {
+	warrants, err := listAllWarrants(&warrant.ListWarrantParams{
+		ObjectType: objectType,
+		ObjectId:   objectId,
+		Relation:   relation,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byRelation := make(map[string][]attachedWarrant)
+	for _, w := range warrants {
+		attached := attachedWarrant{
+			Subject: subjectAsString(w.Subject),
+			Policy:  w.Policy,
+		}
+		if w.Subject.Relation != "" && depth > 0 {
+			if path[attached.Subject] {
+				attached.Cycle = true
+			} else {
+				path[attached.Subject] = true
+				members, err := listInboundWarrants(w.Subject.ObjectType, w.Subject.ObjectId, w.Subject.Relation, depth-1, path)
+				delete(path, attached.Subject)
+				if err != nil {
+					return nil, err
+				}
+				attached.Members = members[w.Subject.Relation]
+			}
+		}
+		byRelation[w.Relation] = append(byRelation[w.Relation], attached)
+	}
+	return byRelation, nil
+}
+
+// Fetch all pages of warrants matching params
+func listAllWarrants(params *warrant.ListWarrantParams) ([]warrant.Warrant, error) {
+	var warrants []warrant.Warrant
+	for {
+		resp, err := warrant.ListWarrants(params)
+		if err != nil {
+			return nil, err
+		}
+		warrants = append(warrants, resp.Results...)
+		if resp.NextCursor == "" {
+			return warrants, nil
+		}
+		params.NextCursor = resp.NextCursor
+	}
+}
+
+func groupByRelation(byRelation map[string][]attachedWarrant) []relationWarrants {
+	groups := make([]relationWarrants, 0, len(byRelation))
+	for relation, warrants := range byRelation {
+		groups = append(groups, relationWarrants{Relation: relation, Warrants: warrants})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Relation < groups[j].Relation
+	})
+	return groups
+}
+
+// Table output, one row per warrant (including userset members, with the userset they're a member via)
+func (o objectWithWarrants) TableRows() any {
+	object := fmt.Sprintf("%s:%s", o.ObjectType, o.ObjectId)
+	rows := make([]objectWarrantRow, 0)
+	var addMembers func(relation string, members []attachedWarrant, via string)
+	addMembers = func(relation string, members []attachedWarrant, via string) {
+		for _, member := range members {
+			rows = append(rows, objectWarrantRow{Direction: "inbound", Relation: relation, Object: object, Subject: member.Subject, Policy: member.Policy, Via: via})
+			addMembers(relation, member.Members, member.Subject)
+		}
+	}
+	for _, group := range o.Inbound {
+		addMembers(group.Relation, group.Warrants, "")
+	}
+	for _, group := range o.Outbound {
+		for _, w := range group.Warrants {
+			subject := w.Subject
+			if subject == "" {
+				subject = object
+			}
+			rows = append(rows, objectWarrantRow{Direction: "outbound", Relation: group.Relation, Object: w.Object, Subject: subject, Policy: w.Policy})
+		}
+	}
+	return rows
+}
+
+func printObjectWithWarrants(o *objectWithWarrants) {
+	object := fmt.Sprintf("%s:%s", o.ObjectType, o.ObjectId)
+	fmt.Println(printer.Style(object).Bold())
+	if len(o.Meta) > 0 {
+		printer.PrintJson(o.Meta)
+	}
+
+	fmt.Println()
+	printer.PrintTree([]printer.TreeNode{
+		warrantsTree(fmt.Sprintf("Inbound warrants (%s is the object)", object), o.Inbound, false),
+	})
+	fmt.Println()
+	printer.PrintTree([]printer.TreeNode{
+		warrantsTree(fmt.Sprintf("Outbound warrants (%s is the subject)", object), o.Outbound, true),
+	})
+}
+
+// A tree of warrants grouped by relation, including the members of followed usersets
+func warrantsTree(title string, groups []relationWarrants, outbound bool) printer.TreeNode {
+	root := printer.TreeNode{Label: title}
+	if len(groups) == 0 {
+		root.Children = []printer.TreeNode{{Label: printer.Style("(none)").Faint().String()}}
+	}
+	for _, group := range groups {
+		root.Children = append(root.Children, printer.TreeNode{
+			Label:    group.Relation,
+			Children: warrantNodes(group.Warrants, outbound),
+		})
+	}
+	return root
+}
+
+func warrantNodes(warrants []attachedWarrant, outbound bool) []printer.TreeNode {
+	var nodes []printer.TreeNode
+	for _, w := range warrants {
+		label := w.Subject
+		if outbound {
+			label = w.Object
+			if w.Subject != "" {
+				label += printer.Style(fmt.Sprintf(" (as %s)", w.Subject)).Faint().String()
+			}
+		}
+		if w.Policy != "" {
+			label += printer.Style(fmt.Sprintf(" [%s]", w.Policy)).Faint().String()
+		}
+		if w.Cycle {
+			label += printer.Style(" (cycle, not followed)").Faint().String()
+		}
+		nodes = append(nodes, printer.TreeNode{
+			Label:    label,
+			Children: warrantNodes(w.Members, outbound),
+		})
+	}
+	return nodes
+}
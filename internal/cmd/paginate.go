@@ -0,0 +1,57 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/warrant-dev/warrant-cli/internal/printer"
+	"github.com/warrant-dev/warrant-go/v6"
+)
+
+// Fetch all pages of a list (e.g. for --all), starting from nextCursor. fetch returns the page at a cursor. If stream
+// is set (see printer.IsStreamable), each page is printed as soon as it's fetched. Otherwise, the results of all pages
+// are returned for the caller to print. If fetching a page fails, the cursor to resume from is printed to stderr.
+func fetchAllPages[T any](nextCursor string, stream bool, fetch func(cursor string) (warrant.ListResponse[T], error)) ([]T, error) {
+	results := []T{}
+	cursor := nextCursor
+	for {
+		page, err := fetch(cursor)
+		if err != nil {
+			if cursor != "" {
+				fmt.Fprintf(os.Stderr, "Resume with --nextCursor %s\n", cursor)
+			}
+			return nil, err
+		}
+		if stream {
+			printer.Print(page.Results)
+		} else {
+			results = append(results, page.Results...)
+		}
+
+		if page.NextCursor == "" {
+			return results, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// Print the cursor for the next page of results (if any) to stderr so scripts can resume from it
+func printNextCursor(nextCursor string) {
+	if nextCursor != "" {
+		fmt.Fprintf(os.Stderr, "%s\n", printer.Style("nextCursor: "+nextCursor).Faint())
+	}
+}
@@ -15,7 +15,12 @@
 package cmd
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	"github.com/spf13/cobra"
+	"github.com/warrant-dev/warrant-cli/internal/config"
 	"github.com/warrant-dev/warrant-cli/internal/printer"
 	"github.com/warrant-dev/warrant-go/v6"
 )
@@ -24,48 +29,319 @@ var queryWarrantToken string
 var queryLimit int
 var queryNextCursor string
 var queryPrevCursor string
+var queryAll bool
+var queryTree bool
+
+var queryParamValues []string
+var saveQueryGlobal bool
 
 func init() {
-	queryCmd.Flags().StringVarP(&queryWarrantToken, "warrant-token", "w", "", "optional warrant token header value to include in query request")
-	queryCmd.Flags().IntVarP(&queryLimit, "limit", "l", 0, "optional query result set limit")
-	queryCmd.Flags().StringVar(&queryNextCursor, "nextCursor", "", "optional nextCursor string for pagination")
-	queryCmd.Flags().StringVar(&queryPrevCursor, "prevCursor", "", "optional prevCursor string for pagination")
+	addQueryFlags(queryCmd)
+	addQueryFlags(runQueryCmd)
+	runQueryCmd.Flags().StringArrayVarP(&queryParamValues, "param", "p", nil, "value for a {{param}} placeholder in the saved query, as param=value (can be repeated)")
+	saveQueryCmd.Flags().BoolVar(&saveQueryGlobal, "global", false, "save to the global config file instead of the current (e.g. project-local) config file")
+
+	queryCmd.AddCommand(saveQueryCmd)
+	queryCmd.AddCommand(runQueryCmd)
+	queryCmd.AddCommand(listQueryCmd)
 	rootCmd.AddCommand(queryCmd)
 }
 
+func addQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&queryWarrantToken, "warrant-token", "w", "", "optional warrant token header value to include in query request")
+	cmd.Flags().IntVarP(&queryLimit, "limit", "l", 0, "optional query result set limit")
+	cmd.Flags().StringVar(&queryNextCursor, "nextCursor", "", "optional nextCursor string for pagination")
+	cmd.Flags().StringVar(&queryPrevCursor, "prevCursor", "", "optional prevCursor string for pagination")
+	cmd.Flags().BoolVar(&queryAll, "all", false, "fetch all results, following nextCursor until there are no more pages")
+	cmd.Flags().BoolVar(&queryTree, "tree", false, "print results as a tree, grouped by object and relation (takes precedence over --output)")
+	cmd.MarkFlagsMutuallyExclusive("all", "prevCursor")
+}
+
 var queryCmd = &cobra.Command{
 	Use:   "query <queryString>",
 	Short: "Run a provided Warrant query",
-	Long:  "Run a provided Warrant query.",
+	Long:  "Run a provided Warrant query. If there are more results, the cursor for the next page is printed to stderr. Use --all to fetch all pages.",
 	Example: `
-warrant query 'select explicit *'`,
+warrant query 'select explicit *'
+warrant query 'select * of type role' --limit 100 --nextCursor <cursor>
+warrant query 'select * of type role' --all -o ndjson
+warrant query 'select * of type user for role:admin' -o table
+warrant query 'select * of type user for role:admin' --tree`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
+
+		return runQuery(args[0])
+	},
+}
+
+var saveQueryCmd = &cobra.Command{
+	Use:   "save <name> <queryString>",
+	Short: "Save a query to run later by name",
+	Long:  "Save a query to run later by name (via 'query run'). Queries can include {{param}} placeholders, replaced with the values provided to 'query run'. Queries are saved to the current config file (e.g. a project-local .warrant.json, so they can be shared through git) or, with --global, the global config file.",
+	Example: `
+warrant query save user-documents 'select * of type document for user:{{user}}'
+warrant query save admins 'select member of type user for role:admin' --global`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, query := args[0], args[1]
+		err := config.ValidateQueryName(name)
+		if err != nil {
+			return printer.UsageError(err)
+		}
 
-		queryParams := &warrant.QueryParams{}
-		if queryWarrantToken != "" {
-			queryParams.RequestOptions = warrant.RequestOptions{
-				WarrantToken: queryWarrantToken,
+		path := cfgFile
+		if saveQueryGlobal {
+			path, err = config.GlobalConfigPath()
+			if err != nil {
+				return err
 			}
 		}
-		if queryLimit > 0 {
-			queryParams.Limit = queryLimit
+		cfg, err := config.LoadConfigFile(path)
+		if err != nil {
+			return printer.ConfigError(err)
 		}
-		if queryNextCursor != "" {
-			queryParams.NextCursor = queryNextCursor
+		if cfg.Queries == nil {
+			cfg.Queries = make(map[string]string)
 		}
-		if queryPrevCursor != "" {
-			queryParams.PrevCursor = queryPrevCursor
+		cfg.Queries[name] = query
+		err = cfg.Write()
+		if err != nil {
+			return err
 		}
+		savedQuery := config.SavedQuery{
+			Name:   name,
+			Query:  query,
+			Params: config.QueryParams(query),
+			Source: cfg.Path(),
+		}
+		printer.PrintChange(savedQuery, func() {
+			fmt.Printf("Saved query '%s' to %s\n", name, cfg.Path())
+		})
+
+		return nil
+	},
+}
 
-		result, err := warrant.Query(args[0], &warrant.QueryParams{})
+var runQueryCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a saved query",
+	Long:  "Run a saved query (see 'query save'), replacing its {{param}} placeholders with the values provided via --param.",
+	Example: `
+warrant query run user-documents --param user=42
+warrant query run admins --all -o table`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		GetApiConfigOrExit()
+
+		savedQuery, err := findSavedQuery(args[0])
 		if err != nil {
 			return err
 		}
+		values := make(map[string]string)
+		for _, paramValue := range queryParamValues {
+			param, value, ok := strings.Cut(paramValue, "=")
+			if !ok {
+				return printer.UsageError(fmt.Errorf("invalid param '%s', must be provided as param=value", paramValue))
+			}
+			values[param] = value
+		}
+		query, err := config.ExpandQuery(savedQuery.Query, values)
+		if err != nil {
+			return printer.UsageError(err)
+		}
+
+		return runQuery(query)
+	},
+}
 
-		printer.PrintJson(result)
+var listQueryCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved queries",
+	Long:  "List saved queries from the current (e.g. project-local) and global config files, including their params.",
+	Example: `
+warrant query list`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		savedQueries, err := config.LoadSavedQueries(cfgFile)
+		if err != nil {
+			return printer.ConfigError(err)
+		}
+
+		printer.PrintResult(savedQueries, func() {
+			if len(savedQueries) == 0 {
+				fmt.Println("No saved queries. Save one with 'warrant query save <name> <queryString>'")
+				return
+			}
+			for _, q := range savedQueries {
+				fmt.Printf("%s\t%s\n", printer.Style(q.Name).Bold(), q.Query)
+			}
+		})
 
 		return nil
 	},
 }
+
+func findSavedQuery(name string) (*config.SavedQuery, error) {
+	savedQueries, err := config.LoadSavedQueries(cfgFile)
+	if err != nil {
+		return nil, printer.ConfigError(err)
+	}
+	for _, q := range savedQueries {
+		if q.Name == name {
+			return &q, nil
+		}
+	}
+	return nil, printer.NewError(printer.ExitNotFound, fmt.Errorf("saved query '%s' not found. Run 'warrant query list' to see saved queries", name))
+}
+
+func runQuery(query string) error {
+	queryParams := &warrant.QueryParams{}
+	if queryWarrantToken != "" {
+		queryParams.RequestOptions = warrant.RequestOptions{
+			WarrantToken: queryWarrantToken,
+		}
+	}
+	if queryLimit > 0 {
+		queryParams.Limit = queryLimit
+	}
+	if queryNextCursor != "" {
+		queryParams.NextCursor = queryNextCursor
+	}
+	if queryPrevCursor != "" {
+		queryParams.PrevCursor = queryPrevCursor
+	}
+
+	if !queryAll {
+		result, err := warrant.Query(query, queryParams)
+		if err != nil {
+			return err
+		}
+		printQueryResults(query, result)
+		printNextCursor(result.NextCursor)
+		return nil
+	}
+
+	stream := printer.IsStreamable() && !queryTree
+	results, err := fetchAllPages(queryParams.NextCursor, stream, func(cursor string) (warrant.ListResponse[warrant.QueryResult], error) {
+		queryParams.NextCursor = cursor
+		return warrant.Query(query, queryParams)
+	})
+	if err != nil {
+		return err
+	}
+	if !stream {
+		printQueryResults(query, warrant.ListResponse[warrant.QueryResult]{Results: results})
+	}
+
+	return nil
+}
+
+// Results of a query. Rendered as a table of subjects, relations and objects (rather than raw results) for table and csv output.
+type queryOutput struct {
+	warrant.ListResponse[warrant.QueryResult]
+	query string
+}
+
+// A query result as a subject, relation and object, along with the warrant (and policy) it's based on
+type queryRow struct {
+	Subject  string `json:"subject"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+	Warrant  string `json:"warrant"`
+	Policy   string `json:"policy"`
+	Implicit bool   `json:"implicit"`
+}
+
+// Queries for objects (e.g. 'select role where user:1 is member') return objects related to the subject in the
+// 'where' clause. Queries for subjects (e.g. 'select member of type user for role:admin') return subjects related
+// to the object in the 'for' clause.
+var queryWhereSubjectRegexp = regexp.MustCompile(`(?i)\bwhere\s+(\S+)\s+is\b`)
+var queryForObjectRegexp = regexp.MustCompile(`(?i)\bfor\s+(\S+)`)
+
+func (o queryOutput) TableRows() any {
+	whereSubject := ""
+	if matches := queryWhereSubjectRegexp.FindStringSubmatch(o.query); matches != nil {
+		whereSubject = matches[1]
+	}
+	forObject := ""
+	if matches := queryForObjectRegexp.FindStringSubmatch(o.query); matches != nil {
+		forObject = matches[1]
+	}
+
+	rows := make([]queryRow, 0, len(o.Results))
+	for _, result := range o.Results {
+		w := result.Warrant
+		row := queryRow{
+			Subject:  subjectAsString(w.Subject),
+			Relation: result.Relation,
+			Object:   fmt.Sprintf("%s:%s", result.ObjectType, result.ObjectId),
+			Warrant: warrantAsString(&warrant.WarrantParams{
+				ObjectType: w.ObjectType,
+				ObjectId:   w.ObjectId,
+				Relation:   w.Relation,
+				Subject:    w.Subject,
+			}),
+			Policy:   w.Policy,
+			Implicit: result.IsImplicit,
+		}
+		if whereSubject != "" {
+			row.Subject = whereSubject
+		} else if forObject != "" {
+			row.Subject = row.Object
+			row.Object = forObject
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func printQueryResults(query string, results warrant.ListResponse[warrant.QueryResult]) {
+	output := queryOutput{
+		ListResponse: results,
+		query:        query,
+	}
+	if queryTree {
+		printQueryTree(output.TableRows().([]queryRow))
+		return
+	}
+	printer.Print(output)
+}
+
+// Print query results as a tree of objects, their relations and the subjects with each relation
+func printQueryTree(rows []queryRow) {
+	var objects []string
+	relationsByObject := make(map[string][]string)
+	rowsByRelation := make(map[string][]queryRow)
+	for _, row := range rows {
+		if _, ok := relationsByObject[row.Object]; !ok {
+			objects = append(objects, row.Object)
+		}
+		key := row.Object + "#" + row.Relation
+		if _, ok := rowsByRelation[key]; !ok {
+			relationsByObject[row.Object] = append(relationsByObject[row.Object], row.Relation)
+		}
+		rowsByRelation[key] = append(rowsByRelation[key], row)
+	}
+
+	var roots []printer.TreeNode
+	for _, object := range objects {
+		root := printer.TreeNode{Label: object}
+		for _, relation := range relationsByObject[object] {
+			relationNode := printer.TreeNode{Label: relation}
+			for _, row := range rowsByRelation[object+"#"+relation] {
+				label := row.Subject
+				if row.Implicit {
+					label += printer.Style(fmt.Sprintf(" (implicit, via %s)", row.Warrant)).Faint().String()
+				}
+				if row.Policy != "" {
+					label += printer.Style(fmt.Sprintf(" [%s]", row.Policy)).Faint().String()
+				}
+				relationNode.Children = append(relationNode.Children, printer.TreeNode{Label: label})
+			}
+			root.Children = append(root.Children, relationNode)
+		}
+		roots = append(roots, root)
+	}
+	printer.PrintTree(roots)
+}
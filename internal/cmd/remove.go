@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/warrant-dev/warrant-cli/internal/printer"
 	"github.com/warrant-dev/warrant-cli/internal/reader"
 	"github.com/warrant-dev/warrant-go/v6"
 )
@@ -35,18 +36,21 @@ warrant remove user:1 editor document:xyz
 warrant remove user:56 member role:admin 'domain == warrant.dev'`,
 	Args: cobra.RangeArgs(3, 4),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		GetConfigOrExit()
+		GetApiConfigOrExit()
 
 		warrantSpec, err := reader.ReadWarrantArgs(args)
 		if err != nil {
 			return err
 		}
 
+		ConfirmChangeOrExit(fmt.Sprintf("remove %s", warrantAsString(warrantSpec)))
 		_, err = warrant.Delete(warrantSpec)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("removed %s\n", warrantAsString(warrantSpec))
+		printer.PrintChange(warrantSpec, func() {
+			fmt.Printf("removed %s\n", warrantAsString(warrantSpec))
+		})
 
 		return nil
 	},
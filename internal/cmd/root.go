@@ -15,18 +15,66 @@
 package cmd
 
 import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/warrant-dev/warrant-cli/internal/config"
 	"github.com/warrant-dev/warrant-cli/internal/printer"
+	"github.com/warrant-dev/warrant-cli/internal/reader"
 	"github.com/warrant-dev/warrant-go/v6"
 )
 
 var cmdConfig *config.Config
+var cfgFile string
+var envName string
+var timeout time.Duration
+var skipConfirmation bool
+var outputFormat string
+var outputTemplate string
+var outputTemplateFile string
+var outputFilter string
+var noColor bool
 
 var rootCmd = &cobra.Command{
 	Use:   "warrant",
 	Short: "Warrant CLI",
 	Long:  `The Warrant CLI is a tool to interact with Warrant via the command line.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		printer.SetUsageHint(fmt.Sprintf("Run '%s --help' for usage.", cmd.CommandPath()))
+		if cmdConfig.IsMissing() && !allowsMissingConfig(cmd) {
+			setErrorOutputFormat()
+			exitWithConfigError(fmt.Sprintf("config file %s does not exist. Run 'warrant init' to create it", cmdConfig.Path()))
+		}
+		applyEnvironmentDefaults(cmd)
+
+		if noColor {
+			printer.DisableColor()
+		}
+
+		err := printer.SetOutputFormat(outputFormat)
+		if err != nil {
+			return printer.UsageError(err)
+		}
+		err = printer.SetTemplate(outputTemplate, outputTemplateFile)
+		if err != nil {
+			return printer.UsageError(err)
+		}
+		err = printer.SetFilter(outputFilter)
+		if err != nil {
+			return printer.UsageError(err)
+		}
+
+		warrant.HttpClient = &http.Client{
+			Timeout:   timeout,
+			Transport: apiTransport,
+		}
+		return nil
+	},
 }
 
 func SetVersion(version string) {
@@ -34,33 +82,169 @@ func SetVersion(version string) {
 }
 
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	wrapUsageErrors(rootCmd)
+
+	cmd, err := rootCmd.ExecuteC()
+	if err != nil {
+		setErrorOutputFormat()
+		cliErr := toCliError(err)
+		// Cobra doesn't expose a distinct error type for unknown commands
+		if strings.HasPrefix(err.Error(), "unknown command") {
+			cliErr = printer.UsageError(err)
+		}
+		printer.SetUsageHint(fmt.Sprintf("Run '%s --help' for usage.", cmd.CommandPath()))
+		printer.ExitWithError(cliErr)
+	}
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.warrant.json)")
-	// rootCmd.PersistentFlags().StringVarP(&EnvName, "env", "e", "", "environment")
-	// viper.BindPFlag("env", rootCmd.PersistentFlags().Lookup("env"))
-	// rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $WARRANT_CONFIG, a .warrant.json in the current or a parent directory, $XDG_CONFIG_HOME/warrant/config.json or $HOME/.warrant.json)")
+	rootCmd.PersistentFlags().StringVarP(&envName, "env", "e", "", "environment to run the command against (overrides the active environment for this invocation only)")
+	cobra.CheckErr(viper.BindPFlag("env", rootCmd.PersistentFlags().Lookup("env")))
+	cobra.CheckErr(viper.BindEnv("env", "WARRANT_ENV"))
+	rootCmd.PersistentFlags().BoolVarP(&skipConfirmation, "yes", "y", false, "skip confirmation prompts for changes to protected environments")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "output format, one of: json, yaml, table, csv, ndjson (default is human-readable text, or json for commands that return data)")
+	rootCmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Go template to format the output with, using the json output's field names, e.g. .results rather than .Results (see https://pkg.go.dev/text/template). Takes precedence over --output")
+	rootCmd.PersistentFlags().StringVar(&outputTemplateFile, "template-file", "", "file containing a Go template to format the output with")
+	rootCmd.PersistentFlags().StringVar(&outputFilter, "filter", "", "jq or JSONPath style expression to select part of the result before printing it (e.g. '.results[].objectId')")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colors and text decoration (also disabled if NO_COLOR is set or output isn't a terminal)")
+	rootCmd.PersistentFlags().BoolVarP(&printer.Quiet, "quiet", "q", false, "print nothing for commands that make changes. check only sets its exit code (1 if the check fails)")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "timeout for requests to the Warrant API (e.g. 10s). No timeout by default")
 }
 
 func initConfig() {
-	cmdConfig = config.LoadConfig()
-	warrant.ApiKey = cmdConfig.Environments[cmdConfig.ActiveEnvironment].ApiKey
-	warrant.ApiEndpoint = cmdConfig.Environments[cmdConfig.ActiveEnvironment].ApiEndpoint
+	var err error
+	cmdConfig, err = config.LoadConfig(cfgFile)
+	if err != nil {
+		setErrorOutputFormat()
+		printer.ExitWithError(printer.ConfigError(err))
+	}
+
+	// --env takes precedence over WARRANT_ENV, which takes precedence over the saved active environment
+	envName = viper.GetString("env")
+	if envName == "" {
+		envName = cmdConfig.ActiveEnvironment
+	}
+	warrant.ApiEndpoint = cmdConfig.Environments[envName].ApiEndpoint
 }
 
 func GetConfigOrExit() *config.Config {
-	if cmdConfig.ActiveEnvironment == "" {
-		printer.PrintErrAndExit("no active environment configured. Run 'warrant init'")
+	if envName == "" {
+		exitWithConfigError("no active environment configured. Run 'warrant init'")
 	}
 	if len(cmdConfig.Environments) == 0 {
-		printer.PrintErrAndExit("no environments configured. Run 'warrant init'")
+		exitWithConfigError("no environments configured. Run 'warrant init'")
 	}
-	if _, ok := cmdConfig.Environments[cmdConfig.ActiveEnvironment]; !ok {
-		printer.PrintErrAndExit("invalid active environment configured. Run 'warrant init'")
+	if _, ok := cmdConfig.Environments[envName]; !ok {
+		if envName != cmdConfig.ActiveEnvironment {
+			exitWithConfigError(fmt.Sprintf("environment '%s' does not exist", envName))
+		}
+		exitWithConfigError("invalid active environment configured. Run 'warrant init'")
 	}
 	return cmdConfig
 }
+
+// Like GetConfigOrExit, but also resolves the current environment's API key (which may read a file or run
+// a credential helper), exiting if it can't be resolved. Only needed by commands that call the Warrant API.
+func GetApiConfigOrExit() *config.Config {
+	cfg := GetConfigOrExit()
+	apiKey, err := currentEnvironment().ResolveApiKey()
+	if err != nil {
+		printer.ExitWithError(printer.ConfigError(fmt.Errorf("unable to resolve API key for environment '%s': %w", envName, err)))
+	}
+	warrant.ApiKey = apiKey
+	return cfg
+}
+
+// Select the --output format for errors that occur before PersistentPreRunE (e.g. config and flag errors), so
+// scripts still get json errors. Flag parsing stops at the first invalid flag, so --output is read from the
+// raw arguments if it wasn't parsed.
+func setErrorOutputFormat() {
+	format := outputFormat
+	if format == "" {
+		format = outputFormatFromArgs(os.Args[1:])
+	}
+	// Invalid formats are reported by PersistentPreRunE, if it runs
+	_ = printer.SetOutputFormat(format)
+}
+
+func outputFormatFromArgs(args []string) string {
+	format := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return format
+		case arg == "-o" || arg == "--output":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--output="):
+			format = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "-o"):
+			format = strings.TrimPrefix(strings.TrimPrefix(arg, "-o"), "=")
+		}
+	}
+	return format
+}
+
+// Whether cmd can run when an explicitly provided config file doesn't exist, i.e. it creates the config file
+// (init and env add) or doesn't use it (help and shell completion)
+func allowsMissingConfig(cmd *cobra.Command) bool {
+	if cmd == initCmd || cmd == addEnvCmd {
+		return true
+	}
+	if cmd.HasParent() && cmd.Parent().Name() == "completion" {
+		return true
+	}
+	return cmd.Name() == "help" || cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd
+}
+
+// The environment the current command runs against
+func currentEnvironment() config.Environment {
+	return cmdConfig.Environments[envName]
+}
+
+// Guard a mutating command (e.g. "delete role:admin"). Exits if the current environment is read-only.
+// If it's protected, the user must confirm (or pass --yes) before continuing.
+func ConfirmChangeOrExit(action string) {
+	env := currentEnvironment()
+	if env.ReadOnly {
+		exitWithConfigError(fmt.Sprintf("environment '%s' is read-only, cannot %s", envName, action))
+	}
+	if !env.Protected || skipConfirmation {
+		return
+	}
+	if !reader.IsTerminal(os.Stdin) {
+		exitWithUsageError(fmt.Sprintf("environment '%s' is protected. Re-run with --yes to %s", envName, action))
+	}
+	confirmed, err := reader.Confirm(fmt.Sprintf("You are about to %s in protected environment '%s'. Continue?", action, printer.Style(envName).Bold()))
+	if err != nil {
+		printer.PrintErrAndExit(err.Error())
+	}
+	if !confirmed {
+		printer.PrintErrAndExit("aborted")
+	}
+}
+
+// Set flags not explicitly provided by the user to the current environment's defaults (if any)
+func applyEnvironmentDefaults(cmd *cobra.Command) {
+	for flagName, value := range currentEnvironment().Defaults.FlagValues() {
+		if value == "" {
+			continue
+		}
+		flag := cmd.Flags().Lookup(flagName)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		err := flag.Value.Set(value)
+		if err != nil {
+			exitWithConfigError(fmt.Sprintf("invalid default %s '%s' for environment '%s': %s", flagName, value, envName, err.Error()))
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	ApiKeySourceEnv     = "env"
+	ApiKeySourceFile    = "file"
+	ApiKeySourceCommand = "command"
+)
+
+// Resolve the environment's API key. If an apiKeySource is configured, the key is read from it:
+//
+//	env:VAR         read from environment variable VAR
+//	file:/path      read from a file (e.g. a mounted secret)
+//	command:<cmd>   run <cmd> (like a git credential helper) and read the key from its stdout
+//
+// Otherwise, the plaintext apiKey is returned. Resolved keys are never written back to the config file.
+func (env Environment) ResolveApiKey() (string, error) {
+	if env.ApiKeySource == "" {
+		return env.ApiKey, nil
+	}
+
+	kind, value, found := strings.Cut(env.ApiKeySource, ":")
+	if !found || value == "" {
+		return "", fmt.Errorf("invalid apiKeySource '%s', must be one of 'env:VAR', 'file:/path' or 'command:<cmd>'", env.ApiKeySource)
+	}
+
+	var apiKey string
+	switch kind {
+	case ApiKeySourceEnv:
+		apiKey = os.Getenv(value)
+		if apiKey == "" {
+			return "", fmt.Errorf("environment variable '%s' (apiKeySource) is not set", value)
+		}
+	case ApiKeySourceFile:
+		path, err := expandHome(value)
+		if err != nil {
+			return "", err
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read apiKeySource file: %w", err)
+		}
+		apiKey = string(contents)
+	case ApiKeySourceCommand:
+		output, err := runCredentialCommand(value)
+		if err != nil {
+			return "", err
+		}
+		apiKey = output
+	default:
+		return "", fmt.Errorf("invalid apiKeySource '%s', must be one of 'env:VAR', 'file:/path' or 'command:<cmd>'", env.ApiKeySource)
+	}
+
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return "", fmt.Errorf("apiKeySource '%s' returned an empty API key", env.ApiKeySource)
+	}
+	return apiKey, nil
+}
+
+func runCredentialCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("apiKeySource command '%s' failed: %w", command, err)
+	}
+	return stdout.String(), nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(path, "~")), nil
+}
@@ -15,65 +15,372 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
-
-	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"path/filepath"
+	"runtime"
 )
 
 var ConfigFileName = ".warrant.json"
+var XdgConfigDirName = "warrant"
+var XdgConfigFileName = "config.json"
+var DefaultApiEndpoint = "https://api.warrant.dev"
+
+// Name of the in-memory environment built from WARRANT_API_KEY and WARRANT_API_ENDPOINT
+var EphemeralEnvironmentName = "default"
+
+// Returned when reading a config file that must be created or upgraded, which requires the config file lock
+var errConfigNeedsWrite = errors.New("config file must be upgraded")
+
+var ErrEphemeralConfig = errors.New("config was loaded from WARRANT_API_KEY and cannot be written. Unset WARRANT_API_KEY to use a config file")
 
 type Config struct {
+	Version           int                    `mapstructure:"version" json:"version"`
 	ActiveEnvironment string                 `mapstructure:"activeEnvironment" json:"activeEnvironment"`
 	Environments      map[string]Environment `mapstructure:"environments" json:"environments"`
+	Queries           map[string]string      `mapstructure:"queries" json:"queries,omitempty"`
+	path              string
+	ephemeral         bool
+	missing           bool
+	extra             map[string]json.RawMessage
+	loaded            *Config // config as originally loaded from disk, used to merge concurrent writes
 }
 
 type Environment struct {
-	ApiKey      string `mapstructure:"apiKey" json:"apiKey"`
-	ApiEndpoint string `mapstructure:"apiEndpoint" json:"apiEndpoint"`
+	ApiKey       string               `mapstructure:"apiKey" json:"apiKey,omitempty"`
+	ApiKeySource string               `mapstructure:"apiKeySource" json:"apiKeySource,omitempty"`
+	ApiEndpoint  string               `mapstructure:"apiEndpoint" json:"apiEndpoint"`
+	Protected    bool                 `mapstructure:"protected" json:"protected,omitempty"`
+	ReadOnly     bool                 `mapstructure:"readOnly" json:"readOnly,omitempty"`
+	Defaults     *EnvironmentDefaults `mapstructure:"defaults" json:"defaults,omitempty"`
+	extra        map[string]json.RawMessage
+}
+
+// Default values used by commands run against an environment when the corresponding flag isn't provided
+type EnvironmentDefaults struct {
+	Output       string                 `mapstructure:"output" json:"output,omitempty"`
+	Timeout      string                 `mapstructure:"timeout" json:"timeout,omitempty"`
+	WarrantToken string                 `mapstructure:"warrantToken" json:"warrantToken,omitempty"`
+	CheckContext map[string]interface{} `mapstructure:"checkContext" json:"checkContext,omitempty"`
+	extra        map[string]json.RawMessage
+}
+
+// Default flag values keyed by flag name
+func (d *EnvironmentDefaults) FlagValues() map[string]string {
+	if d == nil {
+		return nil
+	}
+	return map[string]string{
+		"output":        d.Output,
+		"timeout":       d.Timeout,
+		"warrant-token": d.WarrantToken,
+	}
+}
+
+// Path of the file this config was loaded from (and will be written to)
+func (c Config) Path() string {
+	return c.path
+}
+
+// Whether this config was built in memory from environment variables rather than loaded from a file
+func (c Config) IsEphemeral() bool {
+	return c.ephemeral
+}
+
+// Whether the config file was provided explicitly (via --config or WARRANT_CONFIG) but doesn't exist. It's only
+// created once the config is written (e.g. by 'warrant init').
+func (c Config) IsMissing() bool {
+	return c.missing
 }
 
 func (c Config) Write() error {
-	fileContents, err := json.MarshalIndent(c, "", "    ")
+	if c.ephemeral {
+		return ErrEphemeralConfig
+	}
+	path := c.path
+	if path == "" {
+		var err error
+		path, err = ResolveConfigPath("")
+		if err != nil {
+			return err
+		}
+	}
+
+	unlock, err := lockConfigFile(path)
 	if err != nil {
 		return err
 	}
-	homeDir, err := os.UserHomeDir()
+	defer unlock()
+
+	if c.loaded != nil {
+		latest, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		c = c.rebase(*latest)
+	}
+	c.Version = CurrentVersion
+	fileContents, err := json.MarshalIndent(c, "", "    ")
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile(homeDir+"/"+ConfigFileName, fileContents, 0644)
+	backupPath, err := writeFileAtomic(path, fileContents)
 	if err != nil {
 		return err
 	}
+	if backupPath != "" {
+		fmt.Fprintf(os.Stderr, "Updated config file %s (previous version saved to %s)\n", path, backupPath)
+	}
 	return nil
 }
 
-func LoadConfig() *Config {
-	// Look for .warrant.json in HOME dir and create an empty version if it doesn't exist
+// Resolve the location of the config file. In order of precedence:
+//  1. the given path (i.e. --config)
+//  2. $WARRANT_CONFIG
+//  3. a .warrant.json in the working directory or one of its parents (excluding $HOME)
+//  4. $XDG_CONFIG_HOME/warrant/config.json, if it exists
+//  5. $HOME/.warrant.json
+//
+// If no config file exists yet, it will be created in $XDG_CONFIG_HOME (if set) or $HOME.
+func ResolveConfigPath(path string) (string, error) {
+	if path != "" {
+		return filepath.Abs(path)
+	}
+	if path = os.Getenv("WARRANT_CONFIG"); path != "" {
+		return filepath.Abs(path)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	// Look for a project-local config, walking up from the working directory
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if dir != homeDir && fileExists(filepath.Join(dir, ConfigFileName)) {
+			return filepath.Join(dir, ConfigFileName), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return GlobalConfigPath()
+}
+
+// Location of the user's global (i.e. not project-local) config file: $XDG_CONFIG_HOME/warrant/config.json,
+// if it exists (or $HOME/.warrant.json doesn't), otherwise $HOME/.warrant.json
+func GlobalConfigPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
-	cobra.CheckErr(err)
-	_, err = os.Stat(homeDir + "/" + ConfigFileName)
+	if err != nil {
+		return "", err
+	}
+	homePath := filepath.Join(homeDir, ConfigFileName)
+	if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); xdgConfigHome != "" {
+		xdgPath := filepath.Join(xdgConfigHome, XdgConfigDirName, XdgConfigFileName)
+		if fileExists(xdgPath) || !fileExists(homePath) {
+			return xdgPath, nil
+		}
+	}
+
+	return homePath, nil
+}
+
+// Returns the config file's mode if it stores a plaintext API key and is readable or writable by users other than
+// its owner. Configs that only use apiKeySource (e.g. a project config checked out by git as 0644) hold no secrets.
+func (c Config) InsecurePermissions() (fs.FileMode, bool) {
+	if c.ephemeral || c.path == "" || runtime.GOOS == "windows" || !c.hasPlaintextApiKey() {
+		return 0, false
+	}
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return 0, false
+	}
+	mode := info.Mode().Perm()
+	return mode, mode&0077 != 0
+}
+
+func (c Config) hasPlaintextApiKey() bool {
+	for _, env := range c.Environments {
+		if env.ApiKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func LoadConfig(path string) (*Config, error) {
+	// Build an in-memory config from WARRANT_API_KEY (e.g. in CI) without reading or writing any files
+	if apiKey := os.Getenv("WARRANT_API_KEY"); apiKey != "" {
+		return newEphemeralConfig(apiKey, os.Getenv("WARRANT_API_ENDPOINT")), nil
+	}
+	return LoadConfigFile(path)
+}
+
+// Load the config file at path (resolved as described in ResolveConfigPath). If it doesn't exist, an empty config
+// is returned, and the file is only created once the config is written. If it was provided explicitly (via path or
+// WARRANT_CONFIG), the config is marked as missing (see IsMissing), so a typo in its path doesn't silently start
+// a new config. Unlike LoadConfig, WARRANT_API_KEY is ignored.
+func LoadConfigFile(path string) (*Config, error) {
+	explicit := path != "" || os.Getenv("WARRANT_CONFIG") != ""
+	path, err := ResolveConfigPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if explicit {
+		_, err = os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return &Config{Version: CurrentVersion, path: path, missing: true}, nil
+		}
+	}
+	// Writes are atomic, so the config file is only locked if it must be upgraded
+	contents, err := readConfigContents(path, false)
+	if errors.Is(err, errConfigNeedsWrite) {
+		contents, err = readConfigContentsLocked(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parseConfig(path, contents)
+	if err != nil {
+		return nil, err
+	}
+	// Keep an independent copy of the config as loaded so Write can merge in changes made by other processes
+	loaded, err := parseConfig(path, contents)
+	if err != nil {
+		return nil, err
+	}
+	config.loaded = loaded
+
+	if config.Version > CurrentVersion {
+		fmt.Fprintf(os.Stderr, "Warning: config file %s was written by a newer version of the CLI (config version %d). Consider upgrading.\n", path, config.Version)
+	}
+	for _, key := range config.UnknownKeys() {
+		fmt.Fprintf(os.Stderr, "Warning: unknown key '%s' in config file %s is not supported by this version of the CLI. It will be kept but has no effect\n", key, path)
+	}
+	return config, nil
+}
+
+// Read and parse the config file at path (the caller must hold the config file lock), upgrading it if needed
+func readConfigFile(path string) (*Config, error) {
+	contents, err := readConfigContents(path, true)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(path, contents)
+}
+
+func readConfigContentsLocked(path string) ([]byte, error) {
+	unlock, err := lockConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return readConfigContents(path, true)
+}
+
+// Read the contents of the config file at path, or an empty config if it doesn't exist (it's only created once
+// written). If it's outdated, it's upgraded to the current schema version. This requires write (and the config
+// file lock), otherwise errConfigNeedsWrite is returned.
+func readConfigContents(path string, write bool) ([]byte, error) {
+	contents, err := os.ReadFile(path)
 	if errors.Is(err, fs.ErrNotExist) {
-		emptyJson := []byte("{}")
-		err = os.WriteFile(homeDir+"/"+ConfigFileName, emptyJson, 0644)
-		cobra.CheckErr(err)
+		return json.MarshalIndent(Config{Version: CurrentVersion}, "", "    ")
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// Load config from ~/.warrant.json
-	viper.AddConfigPath(homeDir)
-	viper.SetConfigType("json")
-	viper.SetConfigName(".warrant")
-	viper.AutomaticEnv() // read in environment variables that match
-	err = viper.ReadInConfig()
-	cobra.CheckErr(err)
+	return migrateFile(path, contents, write)
+}
 
-	// Unmarshal config & set warrant client vals
+func parseConfig(path string, contents []byte) (*Config, error) {
 	var config Config
-	err = viper.Unmarshal(&config)
-	cobra.CheckErr(err)
-	return &config
+	err := json.Unmarshal(contents, &config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	config.path = path
+	return &config, nil
+}
+
+// Upgrade an outdated config file in place (keeping a backup of the original) and return its new contents.
+// Returns errConfigNeedsWrite if the file is outdated and write is false.
+func migrateFile(path string, contents []byte, write bool) ([]byte, error) {
+	if len(bytes.TrimSpace(contents)) == 0 {
+		contents = []byte("{}")
+	}
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+	err := decoder.Decode(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	fromVersion, err := rawVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	if fromVersion >= CurrentVersion {
+		return contents, nil
+	}
+	if !write {
+		return nil, errConfigNeedsWrite
+	}
+
+	_, err = migrate(raw)
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := json.MarshalIndent(raw, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	backupPath, err := configBackupPath(path, fmt.Sprintf(".v%d.bak", fromVersion))
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(backupPath, contents, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_, err = writeFileAtomic(path, migrated)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Upgraded config file %s to version %d (backup saved to %s)\n", path, CurrentVersion, backupPath)
+	return migrated, nil
+}
+
+func newEphemeralConfig(apiKey string, apiEndpoint string) *Config {
+	if apiEndpoint == "" {
+		apiEndpoint = DefaultApiEndpoint
+	}
+	return &Config{
+		Version:           CurrentVersion,
+		ActiveEnvironment: EphemeralEnvironmentName,
+		Environments: map[string]Environment{
+			EphemeralEnvironmentName: {
+				ApiKey:      apiKey,
+				ApiEndpoint: apiEndpoint,
+			},
+		},
+		ephemeral: true,
+	}
 }
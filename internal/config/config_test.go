@@ -0,0 +1,72 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigFileNotExist(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, ".cache"))
+	t.Setenv("WARRANT_CONFIG", "")
+	// Don't pick up a project-local config from the repo's parent directories
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		name        string
+		path        string
+		envPath     string
+		wantMissing bool
+		wantPath    string
+	}{
+		{name: "explicit path", path: filepath.Join(dir, "nonexistent", "config.json"), wantMissing: true},
+		{name: "WARRANT_CONFIG", envPath: filepath.Join(dir, "env.json"), wantMissing: true},
+		{name: "default path", wantPath: filepath.Join(dir, ConfigFileName)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WARRANT_CONFIG", tt.envPath)
+			config, err := LoadConfigFile(tt.path)
+			if err != nil {
+				t.Fatalf("LoadConfigFile() error = %v", err)
+			}
+			if config.IsMissing() != tt.wantMissing {
+				t.Errorf("IsMissing() = %t, want %t", config.IsMissing(), tt.wantMissing)
+			}
+			if tt.wantPath != "" && config.Path() != tt.wantPath {
+				t.Errorf("Path() = %s, want %s", config.Path(), tt.wantPath)
+			}
+			if _, err := os.Stat(config.Path()); err == nil {
+				t.Errorf("config file %s was created", config.Path())
+			}
+			if _, err := os.Stat(filepath.Dir(config.Path())); tt.path != "" && err == nil {
+				t.Errorf("config dir %s was created", filepath.Dir(config.Path()))
+			}
+		})
+	}
+}
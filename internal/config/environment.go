@@ -0,0 +1,159 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Shorthand field names accepted by SetField
+var environmentFieldAliases = map[string]string{
+	"endpoint": "apiEndpoint",
+	"key":      "apiKey",
+	"source":   "apiKeySource",
+}
+
+// Check that the environment has a valid endpoint and exactly one way of providing its API key
+func (env Environment) Validate() error {
+	if env.ApiEndpoint == "" {
+		return fmt.Errorf("apiEndpoint is required")
+	}
+	endpoint, err := url.Parse(env.ApiEndpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("invalid apiEndpoint '%s', must be an http(s) url", env.ApiEndpoint)
+	}
+	if env.ApiKey != "" && env.ApiKeySource != "" {
+		return fmt.Errorf("only one of apiKey or apiKeySource can be set")
+	}
+	if env.ApiKeySource != "" {
+		kind, value, found := strings.Cut(env.ApiKeySource, ":")
+		if !found || value == "" || (kind != ApiKeySourceEnv && kind != ApiKeySourceFile && kind != ApiKeySourceCommand) {
+			return fmt.Errorf("invalid apiKeySource '%s', must be one of 'env:VAR', 'file:/path' or 'command:<cmd>'", env.ApiKeySource)
+		}
+	}
+	if env.Defaults != nil && env.Defaults.Timeout != "" {
+		timeout, err := time.ParseDuration(env.Defaults.Timeout)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid defaults.timeout '%s', must be a duration (e.g. '10s')", env.Defaults.Timeout)
+		}
+	}
+	return nil
+}
+
+// Return a copy of the environment with its API key masked for display
+func (env Environment) Masked() Environment {
+	masked := env
+	if len(env.ApiKey) > 8 {
+		masked.ApiKey = strings.Repeat("*", 8) + env.ApiKey[len(env.ApiKey)-4:]
+	} else if env.ApiKey != "" {
+		masked.ApiKey = strings.Repeat("*", 8)
+	}
+	return masked
+}
+
+// Set a single field (by its json name, e.g. 'apiEndpoint' or 'defaults.timeout') to value.
+// An empty value unsets the field. The value is used as a string if possible, otherwise it's
+// parsed as json (e.g. for booleans, numbers and objects).
+func (env Environment) SetField(field string, value string) (Environment, error) {
+	if alias, ok := environmentFieldAliases[field]; ok {
+		field = alias
+	}
+
+	var fields map[string]interface{}
+	contents, err := json.Marshal(env)
+	if err != nil {
+		return env, err
+	}
+	err = json.Unmarshal(contents, &fields)
+	if err != nil {
+		return env, err
+	}
+
+	updated, err := setField(env, fields, strings.Split(field, "."), value)
+	if err != nil {
+		// Retry with the value parsed as json for non-string fields
+		var jsonValue interface{}
+		if json.Unmarshal([]byte(value), &jsonValue) != nil {
+			return env, err
+		}
+		updated, err = setField(env, fields, strings.Split(field, "."), jsonValue)
+		if err != nil {
+			return env, err
+		}
+	}
+	return updated, nil
+}
+
+func setField(env Environment, fields map[string]interface{}, path []string, value interface{}) (Environment, error) {
+	m := deepCopyMap(fields)
+	current := m
+	for _, key := range path[:len(path)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+	key := path[len(path)-1]
+	if value == "" {
+		delete(current, key)
+	} else {
+		current[key] = value
+	}
+
+	contents, err := json.Marshal(m)
+	if err != nil {
+		return Environment{}, err
+	}
+	return ParseEnvironment(contents, env)
+}
+
+func deepCopyMap(m map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			copied[k] = deepCopyMap(nested)
+		} else {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+// Parse an updated version of original from json. Unknown keys are rejected (to catch typos)
+// unless they were already present in original.
+func ParseEnvironment(contents []byte, original Environment) (Environment, error) {
+	var env Environment
+	err := json.Unmarshal(contents, &env)
+	if err != nil {
+		return env, fmt.Errorf("invalid environment: %w", err)
+	}
+
+	existingKeys := make(map[string]bool)
+	for _, key := range original.UnknownKeys("") {
+		existingKeys[key] = true
+	}
+	for _, key := range env.UnknownKeys("") {
+		if !existingKeys[key] {
+			return env, fmt.Errorf("invalid environment: unknown field '%s'", key)
+		}
+	}
+	return env, nil
+}
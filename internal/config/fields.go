@@ -0,0 +1,175 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Unknown keys are kept alongside the fields of Config, Environment and EnvironmentDefaults so
+// that they survive a load/write round trip (e.g. keys written by a newer version of the CLI).
+
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type config Config
+	var decoded config
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*c = Config(decoded)
+	c.extra = extra
+	return nil
+}
+
+func (c Config) MarshalJSON() ([]byte, error) {
+	type config Config
+	return marshalWithExtra(config(c), c.extra)
+}
+
+func (env *Environment) UnmarshalJSON(data []byte) error {
+	type environment Environment
+	var decoded environment
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*env = Environment(decoded)
+	env.extra = extra
+	return nil
+}
+
+func (env Environment) MarshalJSON() ([]byte, error) {
+	type environment Environment
+	return marshalWithExtra(environment(env), env.extra)
+}
+
+func (d *EnvironmentDefaults) UnmarshalJSON(data []byte) error {
+	type defaults EnvironmentDefaults
+	var decoded defaults
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*d = EnvironmentDefaults(decoded)
+	d.extra = extra
+	return nil
+}
+
+func (d EnvironmentDefaults) MarshalJSON() ([]byte, error) {
+	type defaults EnvironmentDefaults
+	return marshalWithExtra(defaults(d), d.extra)
+}
+
+// Keys in the config file not recognized by this version of the CLI (e.g. 'environments.prod.foo')
+func (c Config) UnknownKeys() []string {
+	keys := sortedKeys(c.extra, "")
+	for name, env := range c.Environments {
+		keys = append(keys, env.UnknownKeys("environments."+name+".")...)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Keys in the environment not recognized by this version of the CLI, each prepended with prefix
+func (env Environment) UnknownKeys(prefix string) []string {
+	keys := sortedKeys(env.extra, prefix)
+	if env.Defaults != nil {
+		keys = append(keys, sortedKeys(env.Defaults.extra, prefix+"defaults.")...)
+	}
+	return keys
+}
+
+func sortedKeys(m map[string]json.RawMessage, prefix string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, prefix+k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Unmarshal data into v, returning any keys that don't map to one of v's json fields
+func unmarshalWithExtra(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(v).Elem())
+	var extra map[string]json.RawMessage
+	for k, val := range raw {
+		if known[k] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[k] = val
+	}
+	return extra, nil
+}
+
+// Marshal v, appending extra keys after its fields (in sorted order)
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimSuffix(data, []byte("}")))
+	for i, k := range sortedKeys(extra, "") {
+		if i > 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extra[k])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
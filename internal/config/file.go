@@ -0,0 +1,183 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+)
+
+// Path of the lock file for the config file at path. Lock files are kept in the user's cache directory rather than
+// next to the config file, so project-local config files don't leave untracked files in repos.
+func configLockPath(path string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return configDataPath(filepath.Join(dir, "warrant", "config"), path, ".lock")
+}
+
+// Path of a backup of the config file at path (e.g. '.bak' for the version replaced by the last write). Backups
+// are kept in the user's state directory, which unlike the cache directory isn't purged by the system.
+func configBackupPath(path string, suffix string) (string, error) {
+	dir, err := userStateDir()
+	if err != nil {
+		return "", err
+	}
+	return configDataPath(filepath.Join(dir, "warrant", "backups"), path, suffix)
+}
+
+// Path of a file in dir kept for the config file at path, named after the config file and a hash of its path
+// (e.g. '.warrant.json-<hash>.bak') so files kept for different config files don't collide
+func configDataPath(dir string, path string, suffix string) (string, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", filepath.Base(path), hex.EncodeToString(sum[:6]), suffix)), nil
+}
+
+// The user's state directory: $XDG_STATE_HOME or ~/.local/state, or the user's config directory (as returned by
+// os.UserConfigDir) on Windows and macOS
+func userStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return dir, nil
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return os.UserConfigDir()
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".local", "state"), nil
+}
+
+// Take an advisory lock on the config file at path. Blocks until the lock is acquired.
+func lockConfigFile(path string) (func(), error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+	lockPath, err := configLockPath(path)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = lockFile(lock)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return func() {
+		_ = unlockFile(lock)
+		lock.Close()
+	}, nil
+}
+
+// Replace the file at path with contents by writing to a temp file and renaming it into place, so
+// readers never see a partially written file. The previous version (if any) is kept as a backup (see
+// configBackupPath), whose path is returned.
+func writeFileAtomic(path string, contents []byte) (string, error) {
+	mode := fs.FileMode(0600)
+	backupPath := ""
+	previous, err := os.ReadFile(path)
+	if err == nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		mode = info.Mode().Perm()
+		backupPath, err = configBackupPath(path, ".bak")
+		if err != nil {
+			return "", err
+		}
+		err = os.WriteFile(backupPath, previous, 0600)
+		if err != nil {
+			return "", err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(contents)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	err = os.Chmod(tmp.Name(), mode)
+	if err != nil {
+		return "", err
+	}
+	return backupPath, os.Rename(tmp.Name(), path)
+}
+
+// Apply the changes made to c since it was loaded onto latest (the config currently on disk), so
+// that changes written by other processes in the meantime (e.g. a parallel 'env add') aren't lost.
+func (c Config) rebase(latest Config) Config {
+	if c.loaded == nil {
+		return c
+	}
+
+	rebased := latest
+	rebased.path = c.path
+	rebased.loaded = c.loaded
+	if c.ActiveEnvironment != c.loaded.ActiveEnvironment {
+		rebased.ActiveEnvironment = c.ActiveEnvironment
+	}
+	rebased.Environments = rebaseMap(c.loaded.Environments, c.Environments, latest.Environments)
+	rebased.Queries = rebaseMap(c.loaded.Queries, c.Queries, latest.Queries)
+	return rebased
+}
+
+// Three-way merge of map entries: entries added, changed or removed between loaded and current are applied to latest
+func rebaseMap[T any](loaded map[string]T, current map[string]T, latest map[string]T) map[string]T {
+	merged := make(map[string]T, len(latest))
+	for k, v := range latest {
+		merged[k] = v
+	}
+	for k, v := range current {
+		if loadedVal, ok := loaded[k]; !ok || !reflect.DeepEqual(loadedVal, v) {
+			merged[k] = v
+		}
+	}
+	for k := range loaded {
+		if _, ok := current[k]; !ok {
+			delete(merged, k)
+		}
+	}
+	return merged
+}
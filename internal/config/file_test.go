@@ -0,0 +1,186 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRebaseMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		loaded  map[string]string
+		current map[string]string
+		latest  map[string]string
+		want    map[string]string
+	}{
+		{
+			name:    "no changes",
+			loaded:  map[string]string{"a": "1"},
+			current: map[string]string{"a": "1"},
+			latest:  map[string]string{"a": "1"},
+			want:    map[string]string{"a": "1"},
+		},
+		{
+			name:    "keeps entries added by another process",
+			loaded:  map[string]string{"a": "1"},
+			current: map[string]string{"a": "1"},
+			latest:  map[string]string{"a": "1", "b": "2"},
+			want:    map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:    "applies added entry",
+			loaded:  map[string]string{"a": "1"},
+			current: map[string]string{"a": "1", "c": "3"},
+			latest:  map[string]string{"a": "1", "b": "2"},
+			want:    map[string]string{"a": "1", "b": "2", "c": "3"},
+		},
+		{
+			name:    "applies changed entry",
+			loaded:  map[string]string{"a": "1"},
+			current: map[string]string{"a": "changed"},
+			latest:  map[string]string{"a": "1", "b": "2"},
+			want:    map[string]string{"a": "changed", "b": "2"},
+		},
+		{
+			name:    "local change wins over concurrent change",
+			loaded:  map[string]string{"a": "1"},
+			current: map[string]string{"a": "local"},
+			latest:  map[string]string{"a": "other"},
+			want:    map[string]string{"a": "local"},
+		},
+		{
+			name:    "keeps concurrent change to unchanged entry",
+			loaded:  map[string]string{"a": "1"},
+			current: map[string]string{"a": "1"},
+			latest:  map[string]string{"a": "other"},
+			want:    map[string]string{"a": "other"},
+		},
+		{
+			name:    "applies removed entry",
+			loaded:  map[string]string{"a": "1", "b": "2"},
+			current: map[string]string{"a": "1"},
+			latest:  map[string]string{"a": "1", "b": "2", "c": "3"},
+			want:    map[string]string{"a": "1", "c": "3"},
+		},
+		{
+			name:    "entry removed by another process stays removed",
+			loaded:  map[string]string{"a": "1", "b": "2"},
+			current: map[string]string{"a": "1", "b": "2"},
+			latest:  map[string]string{"a": "1"},
+			want:    map[string]string{"a": "1"},
+		},
+		{
+			name:    "nil maps",
+			loaded:  nil,
+			current: map[string]string{"a": "1"},
+			latest:  nil,
+			want:    map[string]string{"a": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rebaseMap(tt.loaded, tt.current, tt.latest)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rebaseMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebase(t *testing.T) {
+	loaded := Config{
+		ActiveEnvironment: "dev",
+		Environments: map[string]Environment{
+			"dev": {ApiKey: "dev-key", ApiEndpoint: DefaultApiEndpoint},
+		},
+	}
+	latest := Config{
+		ActiveEnvironment: "staging",
+		Environments: map[string]Environment{
+			"dev":     {ApiKey: "dev-key", ApiEndpoint: DefaultApiEndpoint},
+			"staging": {ApiKey: "staging-key", ApiEndpoint: DefaultApiEndpoint},
+		},
+		Queries: map[string]string{"roles": "select * of type role"},
+	}
+
+	tests := []struct {
+		name       string
+		current    Config
+		wantActive string
+		wantEnvs   []string
+		wantPath   string
+	}{
+		{
+			name: "keeps concurrent active environment if unchanged",
+			current: Config{
+				ActiveEnvironment: "dev",
+				Environments: map[string]Environment{
+					"dev":  {ApiKey: "dev-key", ApiEndpoint: DefaultApiEndpoint},
+					"prod": {ApiKey: "prod-key", ApiEndpoint: DefaultApiEndpoint},
+				},
+			},
+			wantActive: "staging",
+			wantEnvs:   []string{"dev", "prod", "staging"},
+		},
+		{
+			name: "applies changed active environment",
+			current: Config{
+				ActiveEnvironment: "prod",
+				Environments: map[string]Environment{
+					"prod": {ApiKey: "prod-key", ApiEndpoint: DefaultApiEndpoint},
+				},
+			},
+			wantActive: "prod",
+			wantEnvs:   []string{"prod", "staging"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := tt.current
+			current.path = "/tmp/.warrant.json"
+			current.loaded = &loaded
+			got := current.rebase(latest)
+			if got.ActiveEnvironment != tt.wantActive {
+				t.Errorf("ActiveEnvironment = %s, want %s", got.ActiveEnvironment, tt.wantActive)
+			}
+			if envs := envNames(got); !reflect.DeepEqual(envs, tt.wantEnvs) {
+				t.Errorf("Environments = %v, want %v", envs, tt.wantEnvs)
+			}
+			if got.Queries["roles"] != latest.Queries["roles"] {
+				t.Errorf("Queries = %v, want %v", got.Queries, latest.Queries)
+			}
+			if got.path != current.path {
+				t.Errorf("path = %s, want %s", got.path, current.path)
+			}
+		})
+	}
+
+	unloaded := Config{ActiveEnvironment: "dev"}
+	if got := unloaded.rebase(latest); got.ActiveEnvironment != "dev" || got.Environments != nil {
+		t.Errorf("rebase() of config that wasn't loaded = %+v, want it unchanged", got)
+	}
+}
+
+func envNames(c Config) []string {
+	var names []string
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Version of the config file schema written by this version of the CLI
+const CurrentVersion = 1
+
+// Upgrades a raw config (as decoded from json) from version-1 to version
+type migration struct {
+	version     int
+	description string
+	migrate     func(raw map[string]interface{}) error
+}
+
+// Migrations in ascending order of version. To change the config schema, bump CurrentVersion
+// and add a migration that upgrades existing config files to it.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "add schema version and default missing apiEndpoints",
+		migrate: func(raw map[string]interface{}) error {
+			envs, _ := raw["environments"].(map[string]interface{})
+			for name, val := range envs {
+				env, ok := val.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("invalid environment '%s'", name)
+				}
+				if endpoint, _ := env["apiEndpoint"].(string); endpoint == "" {
+					env["apiEndpoint"] = DefaultApiEndpoint
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// Apply all migrations newer than the raw config's version. Returns the version the config was upgraded from.
+func migrate(raw map[string]interface{}) (int, error) {
+	fromVersion, err := rawVersion(raw)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range migrations {
+		if m.version <= fromVersion {
+			continue
+		}
+		err = m.migrate(raw)
+		if err != nil {
+			return fromVersion, fmt.Errorf("unable to migrate config to version %d (%s): %w", m.version, m.description, err)
+		}
+		raw["version"] = m.version
+	}
+	return fromVersion, nil
+}
+
+func rawVersion(raw map[string]interface{}) (int, error) {
+	val, ok := raw["version"]
+	if !ok {
+		return 0, nil
+	}
+	number, ok := val.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid config version '%v'", val)
+	}
+	version, err := strconv.Atoi(number.String())
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid config version '%v'", val)
+	}
+	return version, nil
+}
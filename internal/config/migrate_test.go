@@ -0,0 +1,108 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decodeRaw(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(s)))
+	decoder.UseNumber()
+	err := decoder.Decode(&raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name            string
+		raw             string
+		wantFromVersion int
+		want            string
+		wantErr         bool
+	}{
+		{
+			name:            "unversioned config gets version and default endpoints",
+			raw:             `{"activeEnvironment": "dev", "environments": {"dev": {"apiKey": "k"}, "local": {"apiKey": "k", "apiEndpoint": "http://localhost:8000"}}}`,
+			wantFromVersion: 0,
+			want:            `{"version": 1, "activeEnvironment": "dev", "environments": {"dev": {"apiKey": "k", "apiEndpoint": "https://api.warrant.dev"}, "local": {"apiKey": "k", "apiEndpoint": "http://localhost:8000"}}}`,
+		},
+		{
+			name:            "empty config",
+			raw:             `{}`,
+			wantFromVersion: 0,
+			want:            `{"version": 1}`,
+		},
+		{
+			name:            "current version is unchanged",
+			raw:             `{"version": 1, "environments": {"dev": {"apiKey": "k"}}}`,
+			wantFromVersion: 1,
+			want:            `{"version": 1, "environments": {"dev": {"apiKey": "k"}}}`,
+		},
+		{
+			name:            "newer version is unchanged",
+			raw:             `{"version": 99, "foo": "bar"}`,
+			wantFromVersion: 99,
+			want:            `{"version": 99, "foo": "bar"}`,
+		},
+		{
+			name:    "invalid environment",
+			raw:     `{"environments": {"dev": "k"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid version",
+			raw:     `{"version": "1"}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative version",
+			raw:     `{"version": -1}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := decodeRaw(t, tt.raw)
+			fromVersion, err := migrate(raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("migrate() = %v, want error", raw)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("migrate() error = %v", err)
+			}
+			if fromVersion != tt.wantFromVersion {
+				t.Errorf("migrate() fromVersion = %d, want %d", fromVersion, tt.wantFromVersion)
+			}
+			// Compare as json, since migrations set versions as ints and decoded versions are json.Numbers
+			got, _ := json.Marshal(raw)
+			want, _ := json.Marshal(decodeRaw(t, tt.want))
+			if !reflect.DeepEqual(decodeRaw(t, string(got)), decodeRaw(t, string(want))) {
+				t.Errorf("migrate() = %s, want %s", got, want)
+			}
+		})
+	}
+}
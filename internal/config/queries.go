@@ -0,0 +1,132 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Matches {{param}} placeholders in saved queries
+var queryParamRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+var queryNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// A saved query and the config file it was loaded from
+type SavedQuery struct {
+	Name   string   `json:"name"`
+	Query  string   `json:"query"`
+	Params []string `json:"params"`
+	Source string   `json:"source"`
+}
+
+func ValidateQueryName(name string) error {
+	if !queryNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid query name '%s', must only contain letters, numbers, '_', '-' and '.'", name)
+	}
+	return nil
+}
+
+// Names of the {{param}} placeholders in query, in order of first appearance
+func QueryParams(query string) []string {
+	params := []string{}
+	seen := make(map[string]bool)
+	for _, matches := range queryParamRegexp.FindAllStringSubmatch(query, -1) {
+		if !seen[matches[1]] {
+			seen[matches[1]] = true
+			params = append(params, matches[1])
+		}
+	}
+	return params
+}
+
+// Replace the {{param}} placeholders in query with the given values. All placeholders must have a value.
+func ExpandQuery(query string, values map[string]string) (string, error) {
+	params := QueryParams(query)
+	var missing []string
+	for _, param := range params {
+		if _, ok := values[param]; !ok {
+			missing = append(missing, param)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing value for param(s): %s (provide with --param %s=<value>)", strings.Join(missing, ", "), missing[0])
+	}
+	for name := range values {
+		if !slices.Contains(params, name) {
+			return "", fmt.Errorf("unknown param '%s', query has params: %s", name, strings.Join(params, ", "))
+		}
+	}
+
+	return queryParamRegexp.ReplaceAllStringFunc(query, func(placeholder string) string {
+		return values[queryParamRegexp.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
+
+// Load saved queries from the config file at path (resolved as described in ResolveConfigPath) and the global
+// config file. Queries in the (e.g. project-local) config file take precedence over global ones with the same name.
+// Config files aren't created if they don't exist, and WARRANT_API_KEY is ignored.
+func LoadSavedQueries(path string) ([]SavedQuery, error) {
+	path, err := ResolveConfigPath(path)
+	if err != nil {
+		return nil, err
+	}
+	globalPath, err := GlobalConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make(map[string]SavedQuery)
+	for _, p := range []string{globalPath, path} {
+		contents, err := os.ReadFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var config Config
+		if len(strings.TrimSpace(string(contents))) > 0 {
+			err = json.Unmarshal(contents, &config)
+			if err != nil {
+				return nil, fmt.Errorf("invalid config file %s: %w", p, err)
+			}
+		}
+		for name, query := range config.Queries {
+			queries[name] = SavedQuery{
+				Name:   name,
+				Query:  query,
+				Params: QueryParams(query),
+				Source: p,
+			}
+		}
+	}
+
+	savedQueries := make([]SavedQuery, 0, len(queries))
+	for _, q := range queries {
+		savedQueries = append(savedQueries, q)
+	}
+	sort.Slice(savedQueries, func(i, j int) bool {
+		return savedQueries[i].Name < savedQueries[j].Name
+	})
+	return savedQueries, nil
+}
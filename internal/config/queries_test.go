@@ -0,0 +1,110 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryParams(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "select * of type role", want: []string{}},
+		{query: "select * of type document for user:{{user}}", want: []string{"user"}},
+		{query: "select {{relation}} of type {{type}} for user:{{ user }} where {{type}}:x", want: []string{"relation", "type", "user"}},
+		{query: "select * for user:{{user.id}}", want: []string{"user.id"}},
+		{query: "select * for user:{{1user}}", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := QueryParams(tt.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		values  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "no params",
+			query: "select * of type role",
+			want:  "select * of type role",
+		},
+		{
+			name:   "single param",
+			query:  "select * of type document for user:{{user}}",
+			values: map[string]string{"user": "42"},
+			want:   "select * of type document for user:42",
+		},
+		{
+			name:   "repeated param and whitespace",
+			query:  "select {{rel}} of type document for user:{{ user }} where user:{{user}} is {{rel}}",
+			values: map[string]string{"user": "42", "rel": "viewer"},
+			want:   "select viewer of type document for user:42 where user:42 is viewer",
+		},
+		{
+			name:   "empty value",
+			query:  "select * for user:{{user}}",
+			values: map[string]string{"user": ""},
+			want:   "select * for user:",
+		},
+		{
+			name:   "values aren't expanded again",
+			query:  "select * for user:{{user}}",
+			values: map[string]string{"user": "{{user}}"},
+			want:   "select * for user:{{user}}",
+		},
+		{
+			name:    "missing param",
+			query:   "select * for user:{{user}} where {{type}}:1",
+			values:  map[string]string{"user": "42"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown param",
+			query:   "select * for user:{{user}}",
+			values:  map[string]string{"user": "42", "tenant": "acme"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandQuery(tt.query, tt.values)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ExpandQuery() = %s, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandQuery() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandQuery() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
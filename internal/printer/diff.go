@@ -0,0 +1,92 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// A change to the value at a (dotted) path of a json object
+type Change struct {
+	Path string      `json:"path"`
+	Type string      `json:"type"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Changes between two json objects (e.g. an object's meta before and after an update), sorted by path.
+// Nested objects are compared key by key, any other values (including lists) are compared as a whole.
+func Diff(old map[string]interface{}, new map[string]interface{}) []Change {
+	var changes []Change
+	diffObjects("", old, new, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffObjects(prefix string, old map[string]interface{}, new map[string]interface{}, changes *[]Change) {
+	for key, oldVal := range old {
+		path := prefix + key
+		newVal, ok := new[key]
+		if !ok {
+			*changes = append(*changes, Change{Path: path, Type: ChangeRemoved, Old: oldVal})
+			continue
+		}
+		oldObj, oldIsObj := oldVal.(map[string]interface{})
+		newObj, newIsObj := newVal.(map[string]interface{})
+		if oldIsObj && newIsObj {
+			diffObjects(path+".", oldObj, newObj, changes)
+		} else if !reflect.DeepEqual(oldVal, newVal) {
+			*changes = append(*changes, Change{Path: path, Type: ChangeChanged, Old: oldVal, New: newVal})
+		}
+	}
+	for key, newVal := range new {
+		if _, ok := old[key]; !ok {
+			*changes = append(*changes, Change{Path: prefix + key, Type: ChangeAdded, New: newVal})
+		}
+	}
+}
+
+// Print changes one per line, e.g. '+ name: "Admins"' (green), '- legacyFlag: true' (red), '~ count: 1 -> 2' (purple)
+func PrintDiff(changes []Change) {
+	for _, change := range changes {
+		switch change.Type {
+		case ChangeAdded:
+			fmt.Println(Style(fmt.Sprintf("+ %s: %s", change.Path, diffValue(change.New))).Foreground(Green))
+		case ChangeRemoved:
+			fmt.Println(Style(fmt.Sprintf("- %s: %s", change.Path, diffValue(change.Old))).Foreground(Red))
+		default:
+			fmt.Println(Style(fmt.Sprintf("~ %s: %s -> %s", change.Path, diffValue(change.Old), diffValue(change.New))).Foreground(Purple))
+		}
+	}
+}
+
+func diffValue(val interface{}) string {
+	bytes, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+	return string(bytes)
+}
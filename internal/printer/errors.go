@@ -0,0 +1,113 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Exit codes. Scripts can retry on ExitServer and ExitNetwork, other errors won't succeed on retry.
+const (
+	ExitOk         = 0
+	ExitFailure    = 1 // unexpected error, or a failed check/assert
+	ExitUsage      = 2 // invalid command, arguments or flags
+	ExitConfig     = 3 // invalid config file, environment or API key, or a change to a read-only environment
+	ExitAuth       = 4 // API key rejected (HTTP 401/403)
+	ExitNotFound   = 5 // object, object type or warrant not found (HTTP 404)
+	ExitValidation = 6 // invalid request (other HTTP 4xx) or invalid input
+	ExitServer     = 7 // server error or rate limit (HTTP 5xx/429)
+	ExitNetwork    = 8 // unable to reach the Warrant API (e.g. connection refused, timeout)
+)
+
+// An error with the exit code it should be reported with. For API errors, also includes the HTTP status,
+// error code and request id returned by the Warrant API.
+type Error struct {
+	ExitCode  int      `json:"exitCode"`
+	Status    int      `json:"status,omitempty"`
+	Code      string   `json:"code,omitempty"`
+	Message   string   `json:"message"`
+	RequestId string   `json:"requestId,omitempty"`
+	Hints     []string `json:"hints,omitempty"`
+	err       error
+}
+
+func NewError(exitCode int, err error) *Error {
+	return &Error{
+		ExitCode: exitCode,
+		Message:  err.Error(),
+		err:      err,
+	}
+}
+
+func UsageError(err error) *Error {
+	return NewError(ExitUsage, err)
+}
+
+func ConfigError(err error) *Error {
+	return NewError(ExitConfig, err)
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Print err to stderr (as a json object for --output json/ndjson) and exit with its exit code
+func ExitWithError(err error) {
+	var cliErr *Error
+	if !errors.As(err, &cliErr) {
+		cliErr = NewError(ExitFailure, err)
+	}
+
+	if outputFormat == OutputJson || outputFormat == OutputNdjson {
+		bytes, marshalErr := json.Marshal(map[string]*Error{"error": cliErr})
+		if marshalErr == nil {
+			fmt.Fprintln(os.Stderr, string(bytes))
+			os.Exit(cliErr.ExitCode)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "Error:", cliErr.Message)
+	if cliErr.Status != 0 {
+		fmt.Fprintf(os.Stderr, "  status:     %d %s\n", cliErr.Status, http.StatusText(cliErr.Status))
+	}
+	if cliErr.Code != "" {
+		fmt.Fprintf(os.Stderr, "  code:       %s\n", cliErr.Code)
+	}
+	if cliErr.RequestId != "" {
+		fmt.Fprintf(os.Stderr, "  request id: %s\n", cliErr.RequestId)
+	}
+	for _, hint := range cliErr.Hints {
+		fmt.Fprintln(os.Stderr, Style("Hint: "+hint).Faint())
+	}
+	if cliErr.ExitCode == ExitUsage && usageHint != "" {
+		fmt.Fprintln(os.Stderr, usageHint)
+	}
+	os.Exit(cliErr.ExitCode)
+}
+
+// Hint printed after usage errors (e.g. "Run 'warrant check --help' for usage.")
+var usageHint string
+
+func SetUsageHint(hint string) {
+	usageHint = hint
+}
@@ -0,0 +1,247 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A filter selects part of a command's result before it's rendered. Expressions are a subset of jq and JSONPath, e.g.
+//
+//	.results[].warrant.subject.objectId
+//	.results[0].meta
+//	.["key with spaces"]
+//	$.results[*].objectId
+type filter struct {
+	expr     string
+	segments []filterSegment
+}
+
+type filterSegmentKind int
+
+const (
+	filterKey filterSegmentKind = iota
+	filterIndex
+	filterIterate
+)
+
+type filterSegment struct {
+	kind  filterSegmentKind
+	key   string
+	index int
+}
+
+// Filter selected via --filter
+var outputFilter *filter
+
+// Set the expression used to filter command results before they're rendered
+func SetFilter(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		outputFilter = nil
+		return nil
+	}
+	f, err := parseFilter(expr)
+	if err != nil {
+		return err
+	}
+	outputFilter = f
+	return nil
+}
+
+func parseFilter(expr string) (*filter, error) {
+	f := &filter{expr: expr}
+	s := strings.TrimSpace(expr)
+	if strings.HasPrefix(s, "$") {
+		s = s[1:]
+	} else if !strings.HasPrefix(s, ".") {
+		return nil, f.errorf("must start with '.' or '$'")
+	}
+
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, "[") || strings.HasPrefix(s, ".["):
+			s = strings.TrimPrefix(s, ".")
+			end := strings.Index(s, "]")
+			if end == -1 {
+				return nil, f.errorf("missing ']'")
+			}
+			segment, err := f.parseBracket(strings.TrimSpace(s[1:end]))
+			if err != nil {
+				return nil, err
+			}
+			f.segments = append(f.segments, segment)
+			s = s[end+1:]
+		case strings.HasPrefix(s, ".*"):
+			f.segments = append(f.segments, filterSegment{kind: filterIterate})
+			s = s[2:]
+		case strings.HasPrefix(s, "."):
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end == -1 {
+				end = len(s)
+			}
+			key := s[:end]
+			if key == "" {
+				// Identity (e.g. '.'), only allowed as the whole expression
+				if len(s) > 0 || len(f.segments) > 0 {
+					return nil, f.errorf("missing key after '.'")
+				}
+				break
+			}
+			f.segments = append(f.segments, filterSegment{kind: filterKey, key: key})
+			s = s[end:]
+		default:
+			return nil, f.errorf(fmt.Sprintf("unexpected '%s'", s))
+		}
+	}
+	return f, nil
+}
+
+func (f *filter) parseBracket(contents string) (filterSegment, error) {
+	if contents == "" || contents == "*" {
+		return filterSegment{kind: filterIterate}, nil
+	}
+	if len(contents) >= 2 && (contents[0] == '"' || contents[0] == '\'') && contents[len(contents)-1] == contents[0] {
+		return filterSegment{kind: filterKey, key: contents[1 : len(contents)-1]}, nil
+	}
+	index, err := strconv.Atoi(contents)
+	if err != nil {
+		return filterSegment{}, f.errorf(fmt.Sprintf("invalid index '%s'", contents))
+	}
+	return filterSegment{kind: filterIndex, index: index}, nil
+}
+
+func (f *filter) errorf(msg string) error {
+	return fmt.Errorf("invalid filter '%s': %s", f.expr, msg)
+}
+
+// Whether the filter can select multiple values (i.e. iterates over a list or object)
+func (f *filter) isMulti() bool {
+	for _, segment := range f.segments {
+		if segment.kind == filterIterate {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply the filter to val (as returned by toOrderedValue). Returns a list of the selected values if
+// the filter iterates, otherwise the single selected value.
+func (f *filter) apply(val interface{}) (interface{}, error) {
+	vals := []interface{}{val}
+	for _, segment := range f.segments {
+		var next []interface{}
+		for _, v := range vals {
+			selected, err := f.applySegment(segment, v)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, selected...)
+		}
+		vals = next
+	}
+
+	if f.isMulti() {
+		if vals == nil {
+			return []interface{}{}, nil
+		}
+		return vals, nil
+	}
+	return vals[0], nil
+}
+
+func (f *filter) applySegment(segment filterSegment, val interface{}) ([]interface{}, error) {
+	if val == nil {
+		if segment.kind == filterIterate {
+			return nil, nil
+		}
+		return []interface{}{nil}, nil
+	}
+
+	switch segment.kind {
+	case filterKey:
+		obj, ok := val.(*orderedObject)
+		if !ok {
+			return nil, fmt.Errorf("filter '%s': cannot select key '%s' of %s", f.expr, segment.key, describeValue(val))
+		}
+		return []interface{}{obj.values[segment.key]}, nil
+	case filterIndex:
+		arr, ok := val.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("filter '%s': cannot select index %d of %s", f.expr, segment.index, describeValue(val))
+		}
+		index := segment.index
+		if index < 0 {
+			index += len(arr)
+		}
+		if index < 0 || index >= len(arr) {
+			return []interface{}{nil}, nil
+		}
+		return []interface{}{arr[index]}, nil
+	default:
+		switch v := val.(type) {
+		case []interface{}:
+			return v, nil
+		case *orderedObject:
+			vals := make([]interface{}, 0, len(v.keys))
+			for _, k := range v.keys {
+				vals = append(vals, v.values[k])
+			}
+			return vals, nil
+		default:
+			return nil, fmt.Errorf("filter '%s': cannot iterate over %s", f.expr, describeValue(val))
+		}
+	}
+}
+
+func describeValue(val interface{}) string {
+	switch val.(type) {
+	case *orderedObject:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return "null"
+	}
+}
+
+// Print filtered results like 'jq -r': each selected value on its own line, strings unquoted
+func printFiltered(val interface{}, multi bool) error {
+	vals := []interface{}{val}
+	if multi {
+		vals = val.([]interface{})
+	}
+	for _, v := range vals {
+		if s, ok := v.(string); ok {
+			fmt.Printf("%s\n", s)
+			continue
+		}
+		bytes, err := json.MarshalIndent(v, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", string(bytes))
+	}
+	return nil
+}
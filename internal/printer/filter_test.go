@@ -0,0 +1,126 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	key := func(k string) filterSegment { return filterSegment{kind: filterKey, key: k} }
+	index := func(i int) filterSegment { return filterSegment{kind: filterIndex, index: i} }
+	iterate := filterSegment{kind: filterIterate}
+
+	tests := []struct {
+		expr    string
+		want    []filterSegment
+		wantErr bool
+	}{
+		{expr: ".", want: nil},
+		{expr: "$", want: nil},
+		{expr: ".results", want: []filterSegment{key("results")}},
+		{expr: " .results ", want: []filterSegment{key("results")}},
+		{expr: ".results[].warrant.subject.objectId", want: []filterSegment{key("results"), iterate, key("warrant"), key("subject"), key("objectId")}},
+		{expr: ".results[0].meta", want: []filterSegment{key("results"), index(0), key("meta")}},
+		{expr: ".results[-1]", want: []filterSegment{key("results"), index(-1)}},
+		{expr: "$.results[*].objectId", want: []filterSegment{key("results"), iterate, key("objectId")}},
+		{expr: ".meta.*", want: []filterSegment{key("meta"), iterate}},
+		{expr: ".[0]", want: []filterSegment{index(0)}},
+		{expr: `.["key with spaces"]`, want: []filterSegment{key("key with spaces")}},
+		{expr: ".meta['a.b']", want: []filterSegment{key("meta"), key("a.b")}},
+		{expr: "results", wantErr: true},
+		{expr: "[0]", wantErr: true},
+		{expr: "..results", wantErr: true},
+		{expr: ".results.", wantErr: true},
+		{expr: ".results[0", wantErr: true},
+		{expr: ".results[a]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := parseFilter(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseFilter() = %+v, want error", f.segments)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFilter() error = %v", err)
+			}
+			if !reflect.DeepEqual(f.segments, tt.want) {
+				t.Errorf("parseFilter() = %+v, want %+v", f.segments, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	result := map[string]interface{}{
+		"results": []interface{}{
+			map[string]interface{}{"objectId": "admin", "meta": map[string]interface{}{"name": "Admin"}},
+			map[string]interface{}{"objectId": "viewer"},
+		},
+		"count": 2,
+	}
+
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{expr: ".", want: `{"count":2,"results":[{"meta":{"name":"Admin"},"objectId":"admin"},{"objectId":"viewer"}]}`},
+		{expr: ".results[].objectId", want: `["admin","viewer"]`},
+		{expr: ".results[0].meta.name", want: `"Admin"`},
+		{expr: ".results[-1].objectId", want: `"viewer"`},
+		{expr: ".results[5]", want: `null`},
+		{expr: ".results[].meta.name", want: `["Admin",null]`},
+		{expr: ".missing", want: `null`},
+		{expr: ".missing[]", want: `[]`},
+		{expr: ".count.foo", wantErr: true},
+		{expr: ".count[]", wantErr: true},
+		{expr: ".results.objectId", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := parseFilter(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ordered, err := toOrderedValue(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := f.apply(ordered)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("apply() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			bytes, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bytes) != tt.want {
+				t.Errorf("apply() = %s, want %s", bytes, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputJson   = "json"
+	OutputYaml   = "yaml"
+	OutputTable  = "table"
+	OutputCsv    = "csv"
+	OutputNdjson = "ndjson"
+)
+
+var OutputFormats = []string{OutputJson, OutputYaml, OutputTable, OutputCsv, OutputNdjson}
+
+// Renders a command's result in a particular output format
+type Renderer interface {
+	Render(w io.Writer, val any) error
+}
+
+// Implemented by results with a custom table (and csv) representation, e.g. to summarize each item in a few columns
+type Tabular interface {
+	TableRows() any
+}
+
+var renderers = map[string]Renderer{
+	OutputJson:   jsonRenderer{},
+	OutputYaml:   yamlRenderer{},
+	OutputTable:  tableRenderer{},
+	OutputCsv:    csvRenderer{},
+	OutputNdjson: ndjsonRenderer{},
+}
+
+// Output format selected via --output. Empty if none was selected.
+var outputFormat string
+
+func SetOutputFormat(format string) error {
+	if format != "" {
+		if _, ok := renderers[format]; !ok {
+			return fmt.Errorf("invalid output format '%s', must be one of: %s", format, strings.Join(OutputFormats, ", "))
+		}
+	}
+	outputFormat = format
+	return nil
+}
+
+// Whether a structured output format, template or filter was explicitly selected
+func IsStructuredOutput() bool {
+	return outputFormat != "" || outputTemplate != nil || outputFilter != nil
+}
+
+// Whether results can be printed incrementally (e.g. page by page), i.e. ndjson output without a filter or template
+func IsStreamable() bool {
+	return outputFormat == OutputNdjson && outputTemplate == nil && outputFilter == nil
+}
+
+// Print a command's result using the selected filter, template or output format (json if none was selected)
+func Print(val any) {
+	err := render(val)
+	if err != nil {
+		PrintErrAndExit(err.Error())
+	}
+}
+
+func render(val any) error {
+	if outputFilter != nil || outputTemplate != nil {
+		// Filters and templates both see the result as json (e.g. .results[0].objectId), not as Go structs
+		ordered, err := toOrderedValue(val)
+		if err != nil {
+			return err
+		}
+		val = ordered
+	}
+	if outputFilter != nil {
+		filtered, err := outputFilter.apply(val)
+		if err != nil {
+			return err
+		}
+		if outputTemplate == nil && outputFormat == "" {
+			return printFiltered(filtered, outputFilter.isMulti())
+		}
+		val = filtered
+	}
+
+	if outputTemplate != nil {
+		return printTemplate(toPlainValue(val))
+	}
+	format := outputFormat
+	if format == "" {
+		format = OutputJson
+	}
+	return renderers[format].Render(os.Stdout, val)
+}
+
+// Print a command's result using the selected filter, template or output format, or run printText to print it
+// in a human-readable form if none was selected
+func PrintResult(val any, printText func()) {
+	if !IsStructuredOutput() {
+		printText()
+		return
+	}
+	Print(val)
+}
+
+type jsonRenderer struct{}
+
+func (jsonRenderer) Render(w io.Writer, val any) error {
+	bytes, err := json.MarshalIndent(val, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", string(bytes))
+	return err
+}
+
+type ndjsonRenderer struct{}
+
+func (ndjsonRenderer) Render(w io.Writer, val any) error {
+	ordered, err := toOrderedValue(val)
+	if err != nil {
+		return err
+	}
+	for _, item := range toItems(ordered) {
+		bytes, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", string(bytes))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type yamlRenderer struct{}
+
+func (yamlRenderer) Render(w io.Writer, val any) error {
+	ordered, err := toOrderedValue(val)
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	err = encoder.Encode(toYamlNode(ordered))
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func toYamlNode(val interface{}) *yaml.Node {
+	switch v := val.(type) {
+	case *orderedObject:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range v.keys {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, toYamlNode(v.values[k]))
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, toYamlNode(item))
+		}
+		return node
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: v.String()}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprintf("%t", v)}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+}
+
+type tableRenderer struct{}
+
+func (tableRenderer) Render(w io.Writer, val any) error {
+	columns, rows, err := tabulate(val)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	headers := make([]string, 0, len(columns))
+	for _, column := range columns {
+		headers = append(headers, columnHeader(column))
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		cells := make([]string, 0, len(columns))
+		for _, column := range columns {
+			// Tabs and newlines would break column alignment
+			cells = append(cells, strings.NewReplacer("\t", " ", "\n", " ").Replace(row[column]))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+type csvRenderer struct{}
+
+func (csvRenderer) Render(w io.Writer, val any) error {
+	columns, rows, err := tabulate(val)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	err = cw.Write(columns)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, 0, len(columns))
+		for _, column := range columns {
+			record = append(record, row[column])
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func tabulate(val any) ([]string, []map[string]string, error) {
+	if tabular, ok := val.(Tabular); ok {
+		val = tabular.TableRows()
+	}
+	ordered, err := toOrderedValue(val)
+	if err != nil {
+		return nil, nil, err
+	}
+	columns, rows, err := toRows(toItems(ordered))
+	if err != nil || len(columns) > 0 {
+		return columns, rows, err
+	}
+	// Without any results (e.g. an empty list response), take the columns from an empty item so the header is still printed
+	item, ok := zeroItem(val)
+	if !ok {
+		return nil, nil, nil
+	}
+	ordered, err = toOrderedValue(item)
+	if err != nil {
+		return nil, nil, err
+	}
+	columns, _, err = toRows([]interface{}{ordered})
+	return columns, nil, err
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"testing"
+)
+
+type testItem struct {
+	ObjectType string `json:"objectType"`
+	ObjectId   string `json:"objectId"`
+}
+
+type testList struct {
+	Results    []testItem `json:"results,omitempty"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+func TestTableRender(t *testing.T) {
+	tests := []struct {
+		name string
+		val  any
+		want string
+	}{
+		{
+			name: "list response",
+			val:  testList{Results: []testItem{{ObjectType: "role", ObjectId: "admin"}}},
+			want: "OBJECTTYPE   OBJECTID\nrole         admin\n",
+		},
+		{
+			name: "empty list response",
+			val:  testList{},
+			want: "OBJECTTYPE   OBJECTID\n",
+		},
+		{
+			name: "empty slice",
+			val:  []testItem{},
+			want: "OBJECTTYPE   OBJECTID\n",
+		},
+		{
+			name: "empty untyped slice",
+			val:  []interface{}{},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tableRenderer{}.Render(&buf, tt.val)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Render() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	defer SetTemplate("", "")
+
+	tests := []struct {
+		name     string
+		template string
+		val      any
+		want     string
+	}{
+		{
+			name:     "results",
+			template: `{{range .results}}{{.objectId}};{{end}}`,
+			val:      testList{Results: []testItem{{ObjectType: "role", ObjectId: "admin"}, {ObjectType: "role", ObjectId: "viewer"}}},
+			want:     "admin;viewer;",
+		},
+		{
+			name:     "no results",
+			template: `{{range .results}}{{.objectId}};{{end}}`,
+			val:      testList{},
+			want:     "",
+		},
+		{
+			name:     "missing field",
+			template: `{{if .nextCursor}}more{{else}}done{{end}}`,
+			val:      testList{},
+			want:     "done",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetTemplate(tt.template, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ordered, err := toOrderedValue(tt.val)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			err = outputTemplate.Execute(&buf, toPlainValue(ordered))
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Execute() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -16,24 +16,88 @@ package printer
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"runtime"
 
 	"github.com/muesli/termenv"
 )
 
-var Purple = termenv.ColorProfile().Color("#6310FF")
-var Red = termenv.ColorProfile().Color("#FF0000")
-var Green = termenv.ColorProfile().Color("#00FF00")
+// Color profile used to style output. Colors and text decoration are disabled if NO_COLOR is set
+// or stdout isn't a terminal.
+var profile = termenv.EnvColorProfile()
+var Purple termenv.Color
+var Red termenv.Color
+var Green termenv.Color
+var Yellow termenv.Color
 var Checkmark = "✔"
 var Cross = "✖"
+var Warning = "!"
+
+// Whether output of mutating commands (and check results) is suppressed
+var Quiet bool
 
 func init() {
 	if runtime.GOOS == "windows" {
 		Checkmark = "√"
 		Cross = "×"
 	}
+	setColors()
+}
+
+func setColors() {
+	Purple = profile.Color("#6310FF")
+	Red = profile.Color("#FF0000")
+	Green = profile.Color("#00FF00")
+	Yellow = profile.Color("#FFD700")
+}
+
+// Disable colors and text decoration (e.g. via --no-color)
+func DisableColor() {
+	profile = termenv.Ascii
+	setColors()
+}
+
+// Whether output is decorated with colors, bold text and symbols
+func IsDecorated() bool {
+	return profile != termenv.Ascii
+}
+
+// Style text (e.g. Style("config").Bold()). Styles are ignored if output isn't decorated.
+func Style(s ...string) termenv.Style {
+	return profile.String(s...)
+}
+
+// A successful result label (e.g. "✔ true" in green), or just the label if output isn't decorated
+func Success(label string) string {
+	if !IsDecorated() {
+		return label
+	}
+	return Style(Checkmark, label).Foreground(Green).String()
+}
+
+// A failed result label (e.g. "✖ false" in red), or just the label if output isn't decorated
+func Failure(label string) string {
+	if !IsDecorated() {
+		return label
+	}
+	return Style(Cross, label).Foreground(Red).String()
+}
+
+// Print informational text, unless --quiet was provided
+func Printf(format string, a ...any) {
+	if Quiet {
+		return
+	}
+	fmt.Printf(format, a...)
+}
+
+// Print the result of a mutating command (see PrintResult), unless --quiet was provided
+func PrintChange(val any, printText func()) {
+	if Quiet {
+		return
+	}
+	PrintResult(val, printText)
 }
 
 func PrintJson(val any) {
@@ -45,6 +109,5 @@ func PrintJson(val any) {
 }
 
 func PrintErrAndExit(msg string) {
-	fmt.Fprintln(os.Stderr, "Error:", msg)
-	os.Exit(1)
+	ExitWithError(errors.New(msg))
 }
@@ -0,0 +1,70 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Template selected via --template or --template-file. Takes precedence over the output format, if both are set.
+var outputTemplate *template.Template
+
+var templateFuncs = template.FuncMap{
+	"json": func(val any) (string, error) {
+		bytes, err := json.Marshal(val)
+		return string(bytes), err
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Set the Go template used to print command results. The template is read from file, if provided.
+func SetTemplate(text string, file string) error {
+	if text != "" && file != "" {
+		return errors.New("only one of --template and --template-file can be provided")
+	}
+	if file != "" {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		text = string(contents)
+	}
+	if text == "" {
+		outputTemplate = nil
+		return nil
+	}
+
+	tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	outputTemplate = tmpl
+	return nil
+}
+
+func printTemplate(val any) error {
+	err := outputTemplate.Execute(os.Stdout, val)
+	if err != nil {
+		return fmt.Errorf("unable to execute template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import "fmt"
+
+// A node of a tree printed by PrintTree. Labels may be styled (e.g. with Style).
+type TreeNode struct {
+	Label    string
+	Children []TreeNode
+}
+
+// Print each root's (bold) label followed by its descendants as a tree, e.g.
+//
+//	role:admin
+//	└── member
+//	    ├── user:1
+//	    └── user:2
+//
+// Branches are drawn with ASCII characters if output isn't decorated.
+func PrintTree(roots []TreeNode) {
+	for _, root := range roots {
+		fmt.Println(Style(root.Label).Bold())
+		printTreeNodes(root.Children, "")
+	}
+}
+
+func printTreeNodes(nodes []TreeNode, prefix string) {
+	branch, lastBranch, indent, lastIndent := "├── ", "└── ", "│   ", "    "
+	if !IsDecorated() {
+		branch, lastBranch, indent = "|-- ", "`-- ", "|   "
+	}
+	for i, node := range nodes {
+		nodeBranch, childIndent := branch, indent
+		if i == len(nodes)-1 {
+			nodeBranch, childIndent = lastBranch, lastIndent
+		}
+		fmt.Printf("%s%s%s\n", prefix, nodeBranch, node.Label)
+		printTreeNodes(node.Children, prefix+childIndent)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A json object that remembers the order of its keys, so renderers can output fields (and columns)
+// in the same order as the underlying structs.
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Convert val to its json representation, decoded into orderedObjects, []interface{}, json.Numbers, strings, bools and nils
+func toOrderedValue(val interface{}) (interface{}, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decodeOrdered(decoder)
+}
+
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		obj := &orderedObject{values: make(map[string]interface{})}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected json key %v", keyToken)
+			}
+			val, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := obj.values[key]; !exists {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = val
+		}
+		_, err = decoder.Token()
+		return obj, err
+	case '[':
+		arr := make([]interface{}, 0)
+		for decoder.More() {
+			val, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err = decoder.Token()
+		return arr, err
+	default:
+		return nil, fmt.Errorf("unexpected json delimiter %v", delim)
+	}
+}
+
+// Convert an ordered value back to plain maps, e.g. so templates can access object keys
+func toPlainValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case *orderedObject:
+		m := make(map[string]interface{}, len(v.keys))
+		for _, k := range v.keys {
+			m[k] = toPlainValue(v.values[k])
+		}
+		return m
+	case []interface{}:
+		arr := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			arr = append(arr, toPlainValue(item))
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+// The items to render as rows: val itself if it's a list, the 'results' of a list response, or else val as a single item
+func toItems(val interface{}) []interface{} {
+	switch v := val.(type) {
+	case []interface{}:
+		return v
+	case *orderedObject:
+		if results, ok := v.values["results"].([]interface{}); ok {
+			return results
+		}
+		return []interface{}{v}
+	case nil:
+		return []interface{}{}
+	default:
+		return []interface{}{v}
+	}
+}
+
+// Flatten items into rows of cells keyed by column. Nested objects become dotted columns (e.g. 'subject.objectId')
+// and lists are rendered as compact json. Columns are returned in order of first appearance.
+func toRows(items []interface{}) ([]string, []map[string]string, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	rows := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		row := make(map[string]string)
+		var rowColumns []string
+		err := flatten("", item, row, &rowColumns)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, column := range rowColumns {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, nil
+}
+
+func flatten(prefix string, val interface{}, row map[string]string, columns *[]string) error {
+	// Empty objects (e.g. an object without meta) don't add any columns
+	if obj, ok := val.(*orderedObject); ok {
+		for _, k := range obj.keys {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			err := flatten(key, obj.values[k], row, columns)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	cell, err := formatCell(val)
+	if err != nil {
+		return err
+	}
+	row[prefix] = cell
+	*columns = append(*columns, prefix)
+	return nil
+}
+
+func formatCell(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprintf("%t", v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+func columnHeader(column string) string {
+	if column == "" {
+		return "VALUE"
+	}
+	return strings.ToUpper(column)
+}
+
+// The zero value of the items in val, if val is a list (or a list response with 'results') of known type
+func zeroItem(val interface{}) (interface{}, bool) {
+	t := reflect.TypeOf(val)
+	if t == nil {
+		return nil, false
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		field, ok := resultsField(t)
+		if !ok {
+			return nil, false
+		}
+		t = field.Type
+	}
+	if t.Kind() != reflect.Slice {
+		return nil, false
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Interface {
+		return nil, false
+	}
+	return reflect.Zero(elem).Interface(), true
+}
+
+func resultsField(t reflect.Type) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "results" {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Read a --set argument of the form key=value into a (dotted) key path and value. Values that are valid json
+// (e.g. 3, true, null, {"a": 1} or "quoted") are parsed as json, anything else is used as a string.
+func ReadMetaSetArg(arg string) ([]string, interface{}, error) {
+	key, rawValue, ok := strings.Cut(arg, "=")
+	if !ok || key == "" {
+		return nil, nil, fmt.Errorf("invalid --set '%s', must be provided as key=value", arg)
+	}
+	var value interface{}
+	if json.Unmarshal([]byte(rawValue), &value) != nil {
+		value = rawValue
+	}
+	return strings.Split(key, "."), value, nil
+}
+
+// Set the value at a (dotted) key path in meta, creating intermediate objects as needed
+func SetMetaField(meta map[string]interface{}, path []string, value interface{}) error {
+	for i, key := range path[:len(path)-1] {
+		next, ok := meta[key].(map[string]interface{})
+		if !ok {
+			if _, exists := meta[key]; exists {
+				return fmt.Errorf("cannot set '%s', '%s' is not an object", strings.Join(path, "."), strings.Join(path[:i+1], "."))
+			}
+			next = make(map[string]interface{})
+			meta[key] = next
+		}
+		meta = next
+	}
+	meta[path[len(path)-1]] = value
+	return nil
+}
+
+// Remove the value at a (dotted) key path from meta. Returns false if there's no such key.
+func UnsetMetaField(meta map[string]interface{}, path []string) bool {
+	for _, key := range path[:len(path)-1] {
+		next, ok := meta[key].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		meta = next
+	}
+	if _, ok := meta[path[len(path)-1]]; !ok {
+		return false
+	}
+	delete(meta, path[len(path)-1])
+	return true
+}
+
+// Apply a JSON Merge Patch (RFC 7386) to target and return the result. Keys set to null in the patch
+// are removed, objects are merged recursively and any other value replaces the target's value.
+func ApplyMergePatch(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+		} else {
+			targetObj[key] = ApplyMergePatch(targetObj[key], value)
+		}
+	}
+	return targetObj
+}
+
+// Deep copy json-like meta so it can be modified without changing the original
+func CopyMeta(meta map[string]interface{}) map[string]interface{} {
+	if meta == nil {
+		return make(map[string]interface{})
+	}
+	contents, err := json.Marshal(meta)
+	if err != nil {
+		return meta
+	}
+	var copied map[string]interface{}
+	if json.Unmarshal(contents, &copied) != nil {
+		return meta
+	}
+	return copied
+}
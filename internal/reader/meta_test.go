@@ -0,0 +1,184 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reader
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseJson(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var val map[string]interface{}
+	err := json.Unmarshal([]byte(s), &val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return val
+}
+
+// Test cases from RFC 7386, Appendix A
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{target: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{target: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{target: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{target: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{target: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{target: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{target: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{target: `{"e":null}`, patch: `{"a":1}`, want: `{"a":1,"e":null}`},
+		{target: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.patch, func(t *testing.T) {
+			var target, patch, want interface{}
+			for _, v := range []struct {
+				s   string
+				val *interface{}
+			}{{tt.target, &target}, {tt.patch, &patch}, {tt.want, &want}} {
+				err := json.Unmarshal([]byte(v.s), v.val)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := ApplyMergePatch(target, patch)
+			if !reflect.DeepEqual(got, want) {
+				gotJson, _ := json.Marshal(got)
+				t.Errorf("ApplyMergePatch() = %s, want %s", gotJson, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMetaSetArg(t *testing.T) {
+	tests := []struct {
+		arg       string
+		wantPath  []string
+		wantValue interface{}
+		wantErr   bool
+	}{
+		{arg: "name=Admins", wantPath: []string{"name"}, wantValue: "Admins"},
+		{arg: `name="Admins"`, wantPath: []string{"name"}, wantValue: "Admins"},
+		{arg: "name=", wantPath: []string{"name"}, wantValue: ""},
+		{arg: "name=a=b", wantPath: []string{"name"}, wantValue: "a=b"},
+		{arg: "seats=10", wantPath: []string{"seats"}, wantValue: float64(10)},
+		{arg: "enabled=true", wantPath: []string{"enabled"}, wantValue: true},
+		{arg: "legacy=null", wantPath: []string{"legacy"}, wantValue: nil},
+		{arg: `tags=["a","b"]`, wantPath: []string{"tags"}, wantValue: []interface{}{"a", "b"}},
+		{arg: "address.city=Paris", wantPath: []string{"address", "city"}, wantValue: "Paris"},
+		{arg: "name", wantErr: true},
+		{arg: "=value", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			path, value, err := ReadMetaSetArg(tt.arg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ReadMetaSetArg() = %v, %v, want error", path, value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadMetaSetArg() error = %v", err)
+			}
+			if !reflect.DeepEqual(path, tt.wantPath) || !reflect.DeepEqual(value, tt.wantValue) {
+				t.Errorf("ReadMetaSetArg() = %v, %#v, want %v, %#v", path, value, tt.wantPath, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestSetMetaField(t *testing.T) {
+	tests := []struct {
+		name    string
+		meta    string
+		path    string
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "add key", meta: `{"a":1}`, path: "b", value: "x", want: `{"a":1,"b":"x"}`},
+		{name: "replace key", meta: `{"a":1}`, path: "a", value: 2, want: `{"a":2}`},
+		{name: "nested key", meta: `{"a":{"b":1}}`, path: "a.c", value: true, want: `{"a":{"b":1,"c":true}}`},
+		{name: "creates intermediate objects", meta: `{}`, path: "a.b.c", value: "x", want: `{"a":{"b":{"c":"x"}}}`},
+		{name: "replace object", meta: `{"a":{"b":1}}`, path: "a", value: "x", want: `{"a":"x"}`},
+		{name: "non-object parent", meta: `{"a":"x"}`, path: "a.b", value: 1, wantErr: true},
+		{name: "null parent", meta: `{"a":null}`, path: "a.b", value: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := parseJson(t, tt.meta)
+			err := SetMetaField(meta, strings.Split(tt.path, "."), tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SetMetaField() = %v, want error", meta)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetMetaField() error = %v", err)
+			}
+			got, _ := json.Marshal(meta)
+			want, _ := json.Marshal(parseJson(t, tt.want))
+			if string(got) != string(want) {
+				t.Errorf("SetMetaField() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestUnsetMetaField(t *testing.T) {
+	tests := []struct {
+		name        string
+		meta        string
+		path        string
+		want        string
+		wantRemoved bool
+	}{
+		{name: "remove key", meta: `{"a":1,"b":2}`, path: "a", want: `{"b":2}`, wantRemoved: true},
+		{name: "remove nested key", meta: `{"a":{"b":1,"c":2}}`, path: "a.b", want: `{"a":{"c":2}}`, wantRemoved: true},
+		{name: "remove null value", meta: `{"a":null}`, path: "a", want: `{}`, wantRemoved: true},
+		{name: "missing key", meta: `{"a":1}`, path: "b", want: `{"a":1}`},
+		{name: "missing parent", meta: `{"a":1}`, path: "b.c", want: `{"a":1}`},
+		{name: "non-object parent", meta: `{"a":1}`, path: "a.b", want: `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := parseJson(t, tt.meta)
+			removed := UnsetMetaField(meta, strings.Split(tt.path, "."))
+			if removed != tt.wantRemoved {
+				t.Errorf("UnsetMetaField() = %v, want %v", removed, tt.wantRemoved)
+			}
+			got, _ := json.Marshal(meta)
+			want, _ := json.Marshal(parseJson(t, tt.want))
+			if string(got) != string(want) {
+				t.Errorf("meta = %s, want %s", got, want)
+			}
+		})
+	}
+}
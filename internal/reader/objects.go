@@ -0,0 +1,238 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reader
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/warrant-dev/warrant-go/v6"
+)
+
+const (
+	ObjectsFormatCsv    = "csv"
+	ObjectsFormatNdjson = "ndjson"
+	ObjectsFormatText   = "txt"
+)
+
+// An object read from a file, along with the line it was read from. Err is set if the line couldn't be parsed.
+type ObjectRow struct {
+	Line   int
+	Object warrant.ObjectParams
+	Err    error
+}
+
+// Which csv columns hold the object type, object id and meta fields of each object
+type ObjectColumnMapping struct {
+	Type string
+	Id   string
+	// Meta field names keyed by column. If empty, all columns other than the type and id columns are used as meta.
+	Meta map[string]string
+}
+
+// Parse column mappings (e.g. 'type=kind', 'id=user_id', 'meta.name=full_name') into an ObjectColumnMapping.
+// Unmapped type and id columns default to 'objectType' and 'objectId'.
+func ReadObjectColumnMapping(mappings []string) (*ObjectColumnMapping, error) {
+	mapping := &ObjectColumnMapping{
+		Type: "objectType",
+		Id:   "objectId",
+		Meta: make(map[string]string),
+	}
+	for _, m := range mappings {
+		for _, entry := range strings.Split(m, ",") {
+			field, column, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if !ok || column == "" {
+				return nil, fmt.Errorf("invalid column mapping '%s', must be provided as field=column (e.g. id=user_id)", entry)
+			}
+			switch {
+			case field == "type" || field == "objectType":
+				mapping.Type = column
+			case field == "id" || field == "objectId":
+				mapping.Id = column
+			case strings.HasPrefix(field, "meta.") && len(field) > len("meta."):
+				mapping.Meta[column] = strings.TrimPrefix(field, "meta.")
+			default:
+				return nil, fmt.Errorf("invalid column mapping '%s', field must be type, id or meta.<field>", entry)
+			}
+		}
+	}
+	return mapping, nil
+}
+
+// Detect the format of an objects file from its extension: .csv, .ndjson/.jsonl or (otherwise) text
+// with one 'type:id' (or bare id) per line
+func ObjectsFileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ObjectsFormatCsv
+	case ".ndjson", ".jsonl":
+		return ObjectsFormatNdjson
+	default:
+		return ObjectsFormatText
+	}
+}
+
+// Read objects from the file at path (or stdin if path is '-') in the given format. Rows that can't be parsed
+// are returned with Err set rather than failing the whole file.
+func ReadObjectsFile(path string, format string, mapping *ObjectColumnMapping) ([]ObjectRow, error) {
+	var in io.Reader
+	if path == "-" {
+		in = stdin
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	switch format {
+	case ObjectsFormatCsv:
+		return readObjectsCsv(in, mapping)
+	case ObjectsFormatNdjson:
+		return readObjectsNdjson(in)
+	case ObjectsFormatText:
+		return readObjectsText(in)
+	default:
+		return nil, fmt.Errorf("invalid format '%s', must be one of: csv, ndjson, txt", format)
+	}
+}
+
+func readObjectsCsv(in io.Reader, mapping *ObjectColumnMapping) ([]ObjectRow, error) {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+	typeIdx, hasType := columns[mapping.Type]
+	idIdx, hasId := columns[mapping.Id]
+	if !hasId {
+		return nil, fmt.Errorf("csv header has no '%s' column for object ids (map another column with --map id=<column>)", mapping.Id)
+	}
+	for column := range mapping.Meta {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("csv header has no '%s' column", column)
+		}
+	}
+
+	var rows []ObjectRow
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, ObjectRow{Line: parseErr.StartLine, Err: err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		row := ObjectRow{Line: line}
+		if len(record) != len(header) {
+			row.Err = fmt.Errorf("expected %d columns, got %d", len(header), len(record))
+			rows = append(rows, row)
+			continue
+		}
+
+		if hasType {
+			row.Object.ObjectType = record[typeIdx]
+		}
+		row.Object.ObjectId = record[idIdx]
+		meta := make(map[string]interface{})
+		for column, i := range columns {
+			if len(mapping.Meta) > 0 {
+				if field, ok := mapping.Meta[column]; ok {
+					meta[field] = record[i]
+				}
+			} else if column != mapping.Type && column != mapping.Id {
+				meta[column] = record[i]
+			}
+		}
+		if len(meta) > 0 {
+			row.Object.Meta = meta
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readObjectsNdjson(in io.Reader) ([]ObjectRow, error) {
+	var rows []ObjectRow
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		row := ObjectRow{Line: line}
+		err := json.Unmarshal([]byte(text), &row.Object)
+		if err != nil {
+			row.Err = fmt.Errorf("invalid json: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+func readObjectsText(in io.Reader) ([]ObjectRow, error) {
+	var rows []ObjectRow
+	scanner := bufio.NewScanner(in)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		row := ObjectRow{Line: line}
+		// A bare id leaves the type empty so a default (e.g. --type) can be applied
+		if !strings.Contains(text, ":") {
+			row.Object.ObjectId = text
+			rows = append(rows, row)
+			continue
+		}
+		objectType, objectId, err := ReadObjectArg(text)
+		if err != nil {
+			row.Err = fmt.Errorf("invalid object '%s', must be 'type:id' or an id", text)
+		}
+		row.Object.ObjectType = objectType
+		row.Object.ObjectId = objectId
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
@@ -0,0 +1,197 @@
+// Copyright 2023 Forerunner Labs, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/warrant-dev/warrant-go/v6"
+)
+
+// The parts of an ObjectRow compared in tests
+type testRow struct {
+	Line   int
+	Object warrant.ObjectParams
+	Err    bool
+}
+
+func toTestRows(rows []ObjectRow) []testRow {
+	var testRows []testRow
+	for _, row := range rows {
+		testRows = append(testRows, testRow{Line: row.Line, Object: row.Object, Err: row.Err != nil})
+	}
+	return testRows
+}
+
+func TestReadObjectsCsv(t *testing.T) {
+	tests := []struct {
+		name     string
+		csv      string
+		mappings []string
+		want     []testRow
+		wantErr  bool
+	}{
+		{
+			name: "default columns with other columns as meta",
+			csv:  "objectType,objectId,email\nuser,1,a@example.com\nuser,2,b@example.com\n",
+			want: []testRow{
+				{Line: 2, Object: warrant.ObjectParams{ObjectType: "user", ObjectId: "1", Meta: map[string]interface{}{"email": "a@example.com"}}},
+				{Line: 3, Object: warrant.ObjectParams{ObjectType: "user", ObjectId: "2", Meta: map[string]interface{}{"email": "b@example.com"}}},
+			},
+		},
+		{
+			name: "no meta columns",
+			csv:  "objectType,objectId\nrole,admin\n",
+			want: []testRow{
+				{Line: 2, Object: warrant.ObjectParams{ObjectType: "role", ObjectId: "admin"}},
+			},
+		},
+		{
+			name:     "mapped columns only use mapped meta",
+			csv:      "kind,user_id,email,ignored\nuser,1,a@example.com,x\n",
+			mappings: []string{"type=kind,id=user_id", "meta.emailAddress=email"},
+			want: []testRow{
+				{Line: 2, Object: warrant.ObjectParams{ObjectType: "user", ObjectId: "1", Meta: map[string]interface{}{"emailAddress": "a@example.com"}}},
+			},
+		},
+		{
+			name: "missing type column leaves type empty",
+			csv:  "objectId\n1\n",
+			want: []testRow{
+				{Line: 2, Object: warrant.ObjectParams{ObjectId: "1"}},
+			},
+		},
+		{
+			name: "header with whitespace",
+			csv:  "objectType, objectId\nrole,admin\n",
+			want: []testRow{
+				{Line: 2, Object: warrant.ObjectParams{ObjectType: "role", ObjectId: "admin"}},
+			},
+		},
+		{
+			name: "quoted field spanning lines",
+			csv:  "objectType,objectId,description\nrole,admin,\"multi\nline\"\nrole,viewer,x\n",
+			want: []testRow{
+				{Line: 2, Object: warrant.ObjectParams{ObjectType: "role", ObjectId: "admin", Meta: map[string]interface{}{"description": "multi\nline"}}},
+				{Line: 4, Object: warrant.ObjectParams{ObjectType: "role", ObjectId: "viewer", Meta: map[string]interface{}{"description": "x"}}},
+			},
+		},
+		{
+			name: "rows with the wrong number of columns are errors",
+			csv:  "objectType,objectId\nrole,admin,extra\nrole\nrole,viewer\n",
+			want: []testRow{
+				{Line: 2, Err: true},
+				{Line: 3, Err: true},
+				{Line: 4, Object: warrant.ObjectParams{ObjectType: "role", ObjectId: "viewer"}},
+			},
+		},
+		{
+			name: "invalid quoting is a row error",
+			csv:  "objectType,objectId\nrole,\"admin\nrole,viewer\n",
+			want: []testRow{
+				{Line: 2, Err: true},
+			},
+		},
+		{
+			name: "empty file",
+			csv:  "",
+			want: nil,
+		},
+		{
+			name:    "missing id column",
+			csv:     "objectType,id\nrole,admin\n",
+			wantErr: true,
+		},
+		{
+			name:     "missing mapped meta column",
+			csv:      "objectType,objectId\nrole,admin\n",
+			mappings: []string{"meta.name=name"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := ReadObjectColumnMapping(tt.mappings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := readObjectsCsv(strings.NewReader(tt.csv), mapping)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("readObjectsCsv() = %+v, want error", rows)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readObjectsCsv() error = %v", err)
+			}
+			if got := toTestRows(rows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readObjectsCsv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadObjectColumnMapping(t *testing.T) {
+	tests := []struct {
+		mappings []string
+		want     ObjectColumnMapping
+		wantErr  bool
+	}{
+		{mappings: nil, want: ObjectColumnMapping{Type: "objectType", Id: "objectId", Meta: map[string]string{}}},
+		{mappings: []string{"type=kind", "objectId=user_id"}, want: ObjectColumnMapping{Type: "kind", Id: "user_id", Meta: map[string]string{}}},
+		{mappings: []string{"id=user_id, meta.name=full_name"}, want: ObjectColumnMapping{Type: "objectType", Id: "user_id", Meta: map[string]string{"full_name": "name"}}},
+		{mappings: []string{"id"}, wantErr: true},
+		{mappings: []string{"id="}, wantErr: true},
+		{mappings: []string{"meta.=name"}, wantErr: true},
+		{mappings: []string{"name=full_name"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.mappings, " "), func(t *testing.T) {
+			got, err := ReadObjectColumnMapping(tt.mappings)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ReadObjectColumnMapping() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadObjectColumnMapping() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ReadObjectColumnMapping() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadObjectsText(t *testing.T) {
+	text := "role:admin\n\n# comment\n  user:1  \nu2\nrole:a:b\n"
+	want := []testRow{
+		{Line: 1, Object: warrant.ObjectParams{ObjectType: "role", ObjectId: "admin"}},
+		{Line: 4, Object: warrant.ObjectParams{ObjectType: "user", ObjectId: "1"}},
+		{Line: 5, Object: warrant.ObjectParams{ObjectId: "u2"}},
+		{Line: 6, Err: true},
+	}
+	rows, err := readObjectsText(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := toTestRows(rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("readObjectsText() = %+v, want %+v", got, want)
+	}
+}
@@ -18,12 +18,14 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/warrant-dev/warrant-cli/internal/config"
 	"github.com/warrant-dev/warrant-go/v6"
+	"golang.org/x/term"
 )
 
 // Read an objectType and objectId from string
@@ -137,37 +139,108 @@ func ReadWarrantArgs(args []string) (*warrant.WarrantParams, error) {
 	}, nil
 }
 
+// Shared across reads so that buffered input isn't lost between prompts when stdin is piped
+var stdin = bufio.NewReader(os.Stdin)
+
 func PromptAndReadFromStdIn(prompt string) (string, error) {
 	fmt.Println(prompt + ":")
-	buf := bufio.NewReader(os.Stdin)
-	input, err := buf.ReadBytes('\n')
+	input, err := stdin.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || input == "") {
+		return "", err
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// Prompt for a secret (e.g. an API key), hiding input if stdin is a terminal
+func PromptAndReadSecretFromStdIn(prompt string) (string, error) {
+	if !IsTerminal(os.Stdin) {
+		return PromptAndReadFromStdIn(prompt)
+	}
+
+	fmt.Println(prompt + ":")
+	input, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(string(input)), nil
 }
 
-func ReadEnvFromConsole() (string, *config.Environment, error) {
-	envName, err := PromptAndReadFromStdIn("Enter environment name")
-	if err != nil {
-		return "", nil, err
+// Prompt for a yes/no confirmation on stderr. Anything other than 'y' or 'yes' is treated as no.
+func Confirm(prompt string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+	input, err := stdin.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || input == "") {
+		return false, err
 	}
+	answer := strings.ToLower(strings.TrimSpace(input))
+	return answer == "y" || answer == "yes", nil
+}
 
-	apiKey, err := PromptAndReadFromStdIn("Enter API key")
+// Read all of stdin as a single value (e.g. an API key piped in from a secret manager)
+func ReadAllFromStdIn() (string, error) {
+	input, err := io.ReadAll(stdin)
 	if err != nil {
-		return "", nil, err
+		return "", err
 	}
+	return strings.TrimSpace(string(input)), nil
+}
 
-	apiEndpoint, err := PromptAndReadFromStdIn("Warrant endpoint override (leave blank to use default https://api.warrant.dev)")
+// Read a new environment, prompting for any values not already provided. If apiKeyFromStdin is set,
+// the API key is read from stdin and no prompts are shown (the endpoint defaults if not provided).
+func ReadEnv(envName string, apiEndpoint string, apiKeyFromStdin bool) (string, *config.Environment, error) {
+	var apiKey string
+	var err error
+	if apiKeyFromStdin {
+		if envName == "" {
+			return "", nil, fmt.Errorf("--name is required when using --api-key-stdin")
+		}
+		apiKey, err = ReadAllFromStdIn()
+		if err != nil {
+			return "", nil, err
+		}
+		if apiKey == "" {
+			return "", nil, fmt.Errorf("no API key provided on stdin")
+		}
+		if apiEndpoint == "" {
+			apiEndpoint = config.DefaultApiEndpoint
+		}
+	} else {
+		if envName == "" {
+			envName, err = PromptAndReadFromStdIn("Enter environment name")
+			if err != nil {
+				return "", nil, err
+			}
+		}
+
+		apiKey, err = PromptAndReadSecretFromStdIn("Enter API key")
+		if err != nil {
+			return "", nil, err
+		}
+
+		if apiEndpoint == "" {
+			apiEndpoint, err = PromptAndReadFromStdIn(fmt.Sprintf("Warrant endpoint override (leave blank to use default %s)", config.DefaultApiEndpoint))
+			if err != nil {
+				return "", nil, err
+			}
+		}
+		if apiEndpoint == "" {
+			apiEndpoint = config.DefaultApiEndpoint
+		}
+	}
+
+	if envName == "" {
+		return "", nil, fmt.Errorf("environment name is required")
+	}
+
+	env := &config.Environment{
+		ApiKey:      apiKey,
+		ApiEndpoint: apiEndpoint,
+	}
+	err = env.Validate()
 	if err != nil {
 		return "", nil, err
 	}
-	if apiEndpoint == "" {
-		apiEndpoint = "https://api.warrant.dev"
-	}
 
-	return envName, &config.Environment{
-		ApiKey:      apiKey,
-		ApiEndpoint: apiEndpoint,
-	}, nil
+	return envName, env, nil
 }